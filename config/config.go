@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -10,12 +11,40 @@ type Config struct {
 	Server   ServerConfig   `mapstructure:"server"`
 	Database DatabaseConfig `mapstructure:"database"`
 	JWT      JWTConfig      `mapstructure:"jwt"`
+	Auth     AuthConfig     `mapstructure:"auth"`
+	Mail     MailConfig     `mapstructure:"mail"`
+	OIDC     OIDCConfig     `mapstructure:"oidc"`
+	Upload   UploadConfig   `mapstructure:"upload"`
+}
+
+// 上传文件：保存在本地目录，通过 /uploads 访问
+type UploadConfig struct {
+	Dir           string `mapstructure:"dir"`
+	AvatarMaxSize int64  `mapstructure:"avatar_max_size"` // 头像文件大小上限（字节）
 }
 
 type ServerConfig struct {
-	Port string `mapstructure:"port"`
-	Host string `mapstructure:"host"`
-	Mode string `mapstructure:"mode"`
+	Port      string       `mapstructure:"port"`
+	Host      string       `mapstructure:"host"`
+	Mode      string       `mapstructure:"mode"`
+	PublicURL string       `mapstructure:"public_url"` // 对外访问地址，用于邮件中的链接
+	AuthMode  string       `mapstructure:"auth_mode"`  // 登录态：bearer（令牌在响应体中返回）、cookie（另外允许请求头 X-Auth-Mode: cookie 的客户端使用 HttpOnly Cookie，校验 CSRF）
+	Cookie    CookieConfig `mapstructure:"cookie"`
+	CORS      CORSConfig   `mapstructure:"cors"`
+	// 可信反向代理（IP 或 CIDR），只信任来自这些地址的 X-Forwarded-For；为空时使用连接的对端地址
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+// 跨域
+type CORSConfig struct {
+	AllowedOrigins []string `mapstructure:"allowed_origins"` // 允许携带 Cookie 跨域访问的来源，如 https://blog.example.com；其他来源只能不带 Cookie 跨域访问
+}
+
+// 会话 Cookie（auth_mode 为 cookie 时生效）
+type CookieConfig struct {
+	Domain   string `mapstructure:"domain"`
+	Secure   bool   `mapstructure:"secure"`    // 只通过 HTTPS 发送
+	SameSite string `mapstructure:"same_site"` // lax、strict、none（none 要求 secure）
 }
 
 type DatabaseConfig struct {
@@ -27,8 +56,169 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret string `mapstructure:"secret"`
-	Expire string `mapstructure:"expire"`
+	Secret        string `mapstructure:"secret"`
+	Expire        string `mapstructure:"expire"`         // 访问令牌有效期
+	RefreshExpire string `mapstructure:"refresh_expire"` // 刷新令牌有效期
+	Issuer        string `mapstructure:"issuer"`         // 签发者，不同部署（staging/production）必须不同
+	Audience      string `mapstructure:"audience"`       // 受众
+	Leeway        string `mapstructure:"leeway"`         // 允许的时钟偏差
+	Revocation    string `mapstructure:"revocation"`     // 注销列表存储：memory（单实例）、database
+	Algorithm     string `mapstructure:"algorithm"`      // 签名算法：HS256（共享 secret）、RS256、EdDSA
+	KeyRotation   string `mapstructure:"key_rotation"`   // RS256/EdDSA 密钥轮换周期
+	// RS256/EdDSA 私钥加密密钥（base64 编码的 32 字节）
+	KeyEncryptionKey string `mapstructure:"key_encryption_key"`
+}
+
+// 密钥轮换周期，配置缺失或格式错误时默认 30 天
+func (c JWTConfig) KeyRotationDuration() time.Duration {
+	return parseDuration(c.KeyRotation, 30*24*time.Hour)
+}
+
+// 访问令牌有效期，配置缺失或格式错误时默认 15 分钟
+func (c JWTConfig) ExpireDuration() time.Duration {
+	return parseDuration(c.Expire, 15*time.Minute)
+}
+
+// 允许的时钟偏差，配置缺失时不允许偏差
+func (c JWTConfig) LeewayDuration() time.Duration {
+	if c.Leeway == "" {
+		return 0
+	}
+	return parseDuration(c.Leeway, 0)
+}
+
+// 刷新令牌有效期，配置缺失或格式错误时默认 30 天
+func (c JWTConfig) RefreshExpireDuration() time.Duration {
+	return parseDuration(c.RefreshExpire, 30*24*time.Hour)
+}
+
+type AuthConfig struct {
+	PasswordResetExpire string               `mapstructure:"password_reset_expire"` // 密码重置令牌有效期
+	EmailVerifyExpire   string               `mapstructure:"email_verify_expire"`   // 邮箱验证链接有效期
+	MagicLinkExpire     string               `mapstructure:"magic_link_expire"`     // 免密登录链接有效期
+	Login               LoginConfig          `mapstructure:"login"`                 // 登录防暴力破解
+	TwoFactor           TwoFactorConfig      `mapstructure:"two_factor"`            // TOTP 两步验证
+	PasswordPolicy      PasswordPolicyConfig `mapstructure:"password_policy"`       // 密码策略
+	PasswordHash        PasswordHashConfig   `mapstructure:"password_hash"`         // 密码哈希算法
+	WebAuthn            WebAuthnConfig       `mapstructure:"webauthn"`              // 通行密钥
+	Registration        RegistrationConfig   `mapstructure:"registration"`          // 注册方式
+	AccountDeletion     string               `mapstructure:"account_deletion"`      // 注销账号时用户的文章、评论：anonymize（保留并匿名化）、delete（删除）
+	BootstrapAdmin      string               `mapstructure:"bootstrap_admin"`       // 第一个管理员的用户名：还没有管理员时，启动时把该用户设为 admin
+}
+
+type RegistrationConfig struct {
+	Mode         string `mapstructure:"mode"`          // open（开放注册）、invite（必须提供邀请码）
+	InviteQuota  int    `mapstructure:"invite_quota"`  // 普通用户可邀请的人数
+	InviteExpire string `mapstructure:"invite_expire"` // 邀请码默认有效期
+}
+
+// 邀请码默认有效期，配置缺失或格式错误时默认 7 天
+func (c RegistrationConfig) InviteExpireDuration() time.Duration {
+	return parseDuration(c.InviteExpire, 7*24*time.Hour)
+}
+
+type WebAuthnConfig struct {
+	RPID            string   `mapstructure:"rp_id"`            // 依赖方 ID：站点域名，不含协议和端口
+	RPName          string   `mapstructure:"rp_name"`          // 认证器中显示的名称
+	Origins         []string `mapstructure:"origins"`          // 允许的页面来源，为空时使用 server.public_url
+	ChallengeExpire string   `mapstructure:"challenge_expire"` // 注册、登录挑战有效期
+}
+
+// 挑战有效期，配置缺失或格式错误时默认 5 分钟
+func (c WebAuthnConfig) ChallengeExpireDuration() time.Duration {
+	return parseDuration(c.ChallengeExpire, 5*time.Minute)
+}
+
+type PasswordHashConfig struct {
+	Algorithm   string `mapstructure:"algorithm"`   // argon2id、bcrypt
+	Memory      uint32 `mapstructure:"memory"`      // argon2id 内存，KiB
+	Iterations  uint32 `mapstructure:"iterations"`  // argon2id 迭代次数
+	Parallelism uint8  `mapstructure:"parallelism"` // argon2id 并行度
+	BcryptCost  int    `mapstructure:"bcrypt_cost"` // bcrypt 代价
+}
+
+type PasswordPolicyConfig struct {
+	MinLength        int  `mapstructure:"min_length"`
+	MaxLength        int  `mapstructure:"max_length"`
+	RequireLetter    bool `mapstructure:"require_letter"`
+	RequireMixedCase bool `mapstructure:"require_mixed_case"`
+	RequireDigit     bool `mapstructure:"require_digit"`
+	RequireSymbol    bool `mapstructure:"require_symbol"`
+	DisallowUsername bool `mapstructure:"disallow_username"`
+}
+
+type TwoFactorConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`          // 允许用户开启两步验证，开启时必须配置 encryption_key
+	Issuer          string `mapstructure:"issuer"`           // 验证器 App 中显示的服务名称
+	EncryptionKey   string `mapstructure:"encryption_key"`   // TOTP 密钥加密密钥（base64 编码的 32 字节）
+	ChallengeExpire string `mapstructure:"challenge_expire"` // 登录挑战令牌有效期
+}
+
+// 登录挑战令牌有效期，配置缺失或格式错误时默认 5 分钟
+func (c TwoFactorConfig) ChallengeExpireDuration() time.Duration {
+	return parseDuration(c.ChallengeExpire, 5*time.Minute)
+}
+
+type LoginConfig struct {
+	Store         string `mapstructure:"store"`           // 失败记录存储：memory（单实例）、database
+	MaxAttempts   int    `mapstructure:"max_attempts"`    // 同一用户名连续失败次数阈值
+	IPMaxAttempts int    `mapstructure:"ip_max_attempts"` // 同一 IP 连续失败次数阈值
+	Lockout       string `mapstructure:"lockout"`         // 锁定时长
+	DelayBase     string `mapstructure:"delay_base"`      // 递增延迟基数
+	DelayMax      string `mapstructure:"delay_max"`       // 递增延迟上限
+	Window        string `mapstructure:"window"`          // 失败计数窗口
+}
+
+func (c LoginConfig) LockoutDuration() time.Duration {
+	return parseDuration(c.Lockout, 15*time.Minute)
+}
+
+func (c LoginConfig) DelayBaseDuration() time.Duration {
+	return parseDuration(c.DelayBase, time.Second)
+}
+
+func (c LoginConfig) DelayMaxDuration() time.Duration {
+	return parseDuration(c.DelayMax, 30*time.Second)
+}
+
+func (c LoginConfig) WindowDuration() time.Duration {
+	return parseDuration(c.Window, 15*time.Minute)
+}
+
+// 密码重置令牌有效期，配置缺失或格式错误时默认 30 分钟
+func (c AuthConfig) PasswordResetExpireDuration() time.Duration {
+	return parseDuration(c.PasswordResetExpire, 30*time.Minute)
+}
+
+// 邮箱验证链接有效期，配置缺失或格式错误时默认 48 小时
+func (c AuthConfig) EmailVerifyExpireDuration() time.Duration {
+	return parseDuration(c.EmailVerifyExpire, 48*time.Hour)
+}
+
+// 免密登录链接有效期，配置缺失或格式错误时默认 15 分钟
+func (c AuthConfig) MagicLinkExpireDuration() time.Duration {
+	return parseDuration(c.MagicLinkExpire, 15*time.Minute)
+}
+
+// OpenID Connect 单点登录
+type OIDCConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	Issuer       string   `mapstructure:"issuer"` // 提供方地址
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"` // 回调地址，为空时使用 server.public_url + /api/v1/users/oidc/callback
+	Scopes       []string `mapstructure:"scopes"`
+	AllowSignup  bool     `mapstructure:"allow_signup"` // 首次登录时自动创建本地用户
+}
+
+type MailConfig struct {
+	Driver   string `mapstructure:"driver"` // smtp、file（写入 Dir 目录，用于开发和测试）
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+	Dir      string `mapstructure:"dir"`
 }
 
 // func Load() *Config {
@@ -70,6 +260,51 @@ func init() {
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.mode", "debug")
+	viper.SetDefault("server.public_url", "http://localhost:8080")
+	viper.SetDefault("server.auth_mode", "bearer")
+	viper.SetDefault("server.cookie.secure", true)
+	viper.SetDefault("server.cookie.same_site", "lax")
+	viper.SetDefault("auth.password_reset_expire", "30m")
+	viper.SetDefault("auth.email_verify_expire", "48h")
+	viper.SetDefault("auth.magic_link_expire", "15m")
+	viper.SetDefault("auth.login.store", "database")
+	viper.SetDefault("auth.login.max_attempts", 5)
+	viper.SetDefault("auth.login.ip_max_attempts", 20)
+	viper.SetDefault("auth.login.lockout", "15m")
+	viper.SetDefault("auth.login.delay_base", "1s")
+	viper.SetDefault("auth.login.delay_max", "30s")
+	viper.SetDefault("auth.login.window", "15m")
+	viper.SetDefault("auth.two_factor.issuer", "Blog")
+	viper.SetDefault("auth.two_factor.challenge_expire", "5m")
+	viper.SetDefault("auth.password_policy.min_length", 8)
+	viper.SetDefault("auth.password_policy.max_length", 72)
+	viper.SetDefault("auth.password_policy.require_letter", true)
+	viper.SetDefault("auth.password_policy.require_digit", true)
+	viper.SetDefault("auth.password_hash.algorithm", "argon2id")
+	viper.SetDefault("auth.password_hash.memory", 65536)
+	viper.SetDefault("auth.password_hash.iterations", 3)
+	viper.SetDefault("auth.password_hash.parallelism", 2)
+	viper.SetDefault("auth.password_hash.bcrypt_cost", 10)
+	viper.SetDefault("auth.webauthn.rp_id", "localhost")
+	viper.SetDefault("auth.webauthn.rp_name", "Blog")
+	viper.SetDefault("auth.webauthn.challenge_expire", "5m")
+	viper.SetDefault("auth.registration.mode", "open")
+	viper.SetDefault("auth.registration.invite_quota", 5)
+	viper.SetDefault("auth.registration.invite_expire", "168h")
+	viper.SetDefault("auth.account_deletion", "anonymize")
+	viper.SetDefault("oidc.scopes", []string{"openid", "email", "profile"})
+	viper.SetDefault("oidc.allow_signup", true)
+	viper.SetDefault("upload.dir", "uploads")
+	viper.SetDefault("upload.avatar_max_size", 2<<20)
+	viper.SetDefault("mail.driver", "file")
+	viper.SetDefault("mail.dir", "mail")
+	viper.SetDefault("mail.from", "no-reply@example.com")
+	viper.SetDefault("jwt.expire", "15m")
+	viper.SetDefault("jwt.refresh_expire", "720h")
+	viper.SetDefault("jwt.leeway", "30s")
+	viper.SetDefault("jwt.revocation", "database")
+	viper.SetDefault("jwt.algorithm", "HS256")
+	viper.SetDefault("jwt.key_rotation", "720h")
 
 	// 读取配置文件
 	if err := viper.ReadInConfig(); err != nil {
@@ -93,3 +328,16 @@ func Load() *Config {
 	// 返回解析成功的配置对象
 	return &config
 }
+
+// 解析时长配置（如 "15m"、"720h"），为空或格式错误时返回默认值
+func parseDuration(value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: invalid duration %q, using default %v", value, def)
+		return def
+	}
+	return d
+}
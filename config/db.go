@@ -251,6 +251,76 @@ func CleanupDB(db *gorm.DB) error {
 	if err := db.Exec("DELETE FROM comments").Error; err != nil {
 		return err
 	}
+	if err := db.Exec("DELETE FROM refresh_tokens").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM revoked_tokens").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM signing_keys").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM password_reset_tokens").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM login_attempts").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM two_factors").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM recovery_codes").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM personal_access_tokens").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM user_identities").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM oidc_login_states").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM sessions").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM magic_link_tokens").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM web_authn_credentials").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM web_authn_challenges").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM invites").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM invite_redemptions").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM post_revisions").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM post_tags").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM tags").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM categories").Error; err != nil {
+		return err
+	}
+	// 角色权限可能被测试修改，清空后由 SeedDefaultRoles 重新创建
+	if err := db.Exec("DELETE FROM role_permissions").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM roles").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM permissions").Error; err != nil {
+		return err
+	}
 
 	// 重置 SQLite 的 AUTOINCREMENT 序列（确保 ID 从 1 开始）
 	if err := db.Exec("DELETE FROM sqlite_sequence WHERE name='users'").Error; err != nil {
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+type AccessTokenHandler struct {
+	accessTokenService *services.AccessTokenService
+}
+
+func NewAccessTokenHandler(accessTokenService *services.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{
+		accessTokenService: accessTokenService,
+	}
+}
+
+// 创建个人访问令牌
+func (h *AccessTokenHandler) CreateAccessToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	token, err := h.accessTokenService.CreateAccessToken(userID.(uint), req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, token)
+}
+
+// 查询个人访问令牌
+func (h *AccessTokenHandler) ListAccessTokens(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tokens, err := h.accessTokenService.ListAccessTokens(userID.(uint))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, tokens)
+}
+
+// 撤销个人访问令牌
+func (h *AccessTokenHandler) RevokeAccessToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	if err := h.accessTokenService.RevokeAccessToken(userID.(uint), uint(uintid)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, true)
+}
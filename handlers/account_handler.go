@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/middleware"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+type AccountHandler struct {
+	accountService *services.AccountService
+	userService    *services.UserService
+}
+
+func NewAccountHandler(accountService *services.AccountService, userService *services.UserService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		userService:    userService,
+	}
+}
+
+// 导出个人数据：以 zip 附件下载
+func (h *AccountHandler) Export(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := h.userService.GetUserByID(userID.(uint))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("account-%d-%s.zip", user.ID, time.Now().Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	// 响应头已发出，出错时只能中断下载
+	if err := h.accountService.Export(user, c.Writer); err != nil {
+		log.Printf("export account %d failed: %v", user.ID, err)
+		c.Abort()
+	}
+}
+
+// 注销账号：此后无法再登录。没有密码的用户（SSO、免密登录、通行密钥）重新登录后可省略密码
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// 请求体可选（重新登录后注销、未开启两步验证时）
+	var req models.DeleteAccountRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(c, utils.ParseValidationErrors(err))
+			return
+		}
+	}
+
+	var sessionID uint
+	if claims, ok := c.Get("claims"); ok {
+		sessionID = claims.(*utils.Claims).SessionID
+	}
+	if err := h.accountService.DeleteAccount(userID.(uint), sessionID, req); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	middleware.ClearSessionCookies(c)
+	utils.Success(c, true)
+}
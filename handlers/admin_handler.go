@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+// 管理后台：用户、角色管理及文章、评论审核
+type AdminHandler struct {
+	userService    *services.UserService
+	roleService    *services.RoleService
+	postService    *services.PostService
+	commentService *services.CommentService
+}
+
+func NewAdminHandler(userService *services.UserService, roleService *services.RoleService, postService *services.PostService, commentService *services.CommentService) *AdminHandler {
+	return &AdminHandler{
+		userService:    userService,
+		roleService:    roleService,
+		postService:    postService,
+		commentService: commentService,
+	}
+}
+
+// 查询用户列表
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	pageNo, pageSize := utils.GetQueryPage(c)
+	users, err := h.userService.ListUsers(pageNo, pageSize)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	responses := make([]models.UserResponse, 0, len(users))
+	for i := range users {
+		responses = append(responses, newUserResponse(&users[i]))
+	}
+	utils.Success(c, responses)
+}
+
+// 分配用户角色
+func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	var req models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	user, err := h.roleService.UpdateUserRole(uint(uintid), req.Role)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.Success(c, newUserResponse(user))
+}
+
+// 查询角色及权限
+func (h *AdminHandler) ListRoles(c *gin.Context) {
+	roles, err := h.roleService.ListRoles()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, roles)
+}
+
+// 修改角色权限
+func (h *AdminHandler) UpdateRolePermissions(c *gin.Context) {
+	var req models.UpdateRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	role, err := h.roleService.UpdateRolePermissions(c.Param("name"), req.Permissions)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, role)
+}
+
+// 删除任意文章
+func (h *AdminHandler) DeletePost(c *gin.Context) {
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	r, err := h.postService.ModerateDeletePost(uint(uintid))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, r)
+}
+
+// 修改文章审计状态
+func (h *AdminHandler) UpdatePostAuditStatus(c *gin.Context) {
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	var req models.UpdateAuditStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	post, err := h.postService.UpdateAuditStatus(uint(uintid), req.AuditStatus)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, post)
+}
+
+// 删除任意评论
+func (h *AdminHandler) DeleteComment(c *gin.Context) {
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	r, err := h.commentService.ModerateDeleteComment(uint(uintid))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, r)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+type AvatarHandler struct {
+	avatarService *services.AvatarService
+}
+
+func NewAvatarHandler(avatarService *services.AvatarService) *AvatarHandler {
+	return &AvatarHandler{
+		avatarService: avatarService,
+	}
+}
+
+// 上传头像：multipart/form-data，文件字段为 avatar
+func (h *AvatarHandler) UploadAvatar(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// 限制请求体大小（留出表单其他部分的余量），超出时解析表单失败
+	maxSize := h.avatarService.MaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+64<<10)
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.HandleError(c, utils.NewAppErrorWithDetails(413, "Avatar too large", map[string]int64{"max_size": maxSize}))
+			return
+		}
+		utils.HandleError(c, utils.NewAppError(400, "Avatar file required"))
+		return
+	}
+	if header.Size > maxSize {
+		utils.HandleError(c, utils.NewAppErrorWithDetails(413, "Avatar too large", map[string]int64{"max_size": maxSize}))
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	user, err := h.avatarService.UploadAvatar(userID.(uint), data)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, newUserResponse(user))
+}
+
+// 删除头像
+func (h *AvatarHandler) DeleteAvatar(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := h.avatarService.DeleteAvatar(userID.(uint))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, newUserResponse(user))
+}
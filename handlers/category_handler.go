@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+// 文章分类
+type CategoryHandler struct {
+	categoryService *services.CategoryService
+}
+
+func NewCategoryHandler(categoryService *services.CategoryService) *CategoryHandler {
+	return &CategoryHandler{categoryService: categoryService}
+}
+
+// 查询分类树及文章数
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	categories, err := h.categoryService.ListCategoryTree()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, categories)
+}
+
+// 创建分类（管理后台）
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req models.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	category, err := h.categoryService.CreateCategory(req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, category)
+}
+
+// 修改分类（管理后台）
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	var req models.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	category, err := h.categoryService.UpdateCategory(uint(uintid), req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, category)
+}
+
+// 删除分类（管理后台）
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	if err := h.categoryService.DeleteCategory(uint(uintid)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, true)
+}
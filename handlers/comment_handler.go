@@ -55,7 +55,7 @@ func (h *CommentHandler) ListCommentByPostId(c *gin.Context) {
 		return
 	}
 	pageNo, pageSize := utils.GetQueryPage(c)
-	comments, err := h.commentService.ListCommentByPostId(uint(uintid), pageNo, pageSize)
+	comments, err := h.commentService.ListCommentByPostId(uint(uintid), viewerID(c), pageNo, pageSize)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
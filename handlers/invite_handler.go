@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+type InviteHandler struct {
+	inviteService *services.InviteService
+}
+
+func NewInviteHandler(inviteService *services.InviteService) *InviteHandler {
+	return &InviteHandler{
+		inviteService: inviteService,
+	}
+}
+
+// 创建邀请码：普通用户受配额限制，拥有 invites:manage 权限时不限
+func (h *InviteHandler) CreateInvite(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.CreateInviteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(c, utils.ParseValidationErrors(err))
+			return
+		}
+	}
+
+	invite, err := h.inviteService.CreateInvite(claims.(*utils.Claims).UserID, canManageInvites(claims.(*utils.Claims)), req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, invite)
+}
+
+// 查询自己创建的邀请码及剩余配额
+func (h *InviteHandler) ListInvites(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	invites, err := h.inviteService.ListInvites(claims.(*utils.Claims).UserID, canManageInvites(claims.(*utils.Claims)))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, invites)
+}
+
+// 撤销自己创建的邀请码
+func (h *InviteHandler) RevokeInvite(c *gin.Context) {
+	h.revokeInvite(c, false)
+}
+
+// 查询全部邀请码（管理后台）
+func (h *InviteHandler) AdminListInvites(c *gin.Context) {
+	pageNo, pageSize := utils.GetQueryPage(c)
+	invites, err := h.inviteService.ListAllInvites(pageNo, pageSize)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, invites)
+}
+
+// 撤销任意邀请码（管理后台）
+func (h *InviteHandler) AdminRevokeInvite(c *gin.Context) {
+	h.revokeInvite(c, true)
+}
+
+func (h *InviteHandler) revokeInvite(c *gin.Context, all bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	if err := h.inviteService.RevokeInvite(userID.(uint), uint(uintid), all); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, true)
+}
+
+func canManageInvites(claims *utils.Claims) bool {
+	return slices.Contains(claims.Permissions, models.PermInvitesManage)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/utils"
+)
+
+type KeyHandler struct {
+	keys *utils.KeySet // HS256 模式下为 nil
+}
+
+func NewKeyHandler(keys *utils.KeySet) *KeyHandler {
+	return &KeyHandler{
+		keys: keys,
+	}
+}
+
+// 公开验签公钥（RFC 7517 格式，不使用统一响应包装）
+func (h *KeyHandler) JWKS(c *gin.Context) {
+	jwks := utils.JWKS{Keys: []utils.JWK{}}
+	if h.keys != nil {
+		jwks = h.keys.JWKS()
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwks)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService *services.MagicLinkService
+	tokenService     *services.TokenService
+	twoFactorService *services.TwoFactorService
+}
+
+func NewMagicLinkHandler(magicLinkService *services.MagicLinkService, tokenService *services.TokenService, twoFactorService *services.TwoFactorService) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+	}
+}
+
+// 发送登录链接
+func (h *MagicLinkHandler) SendMagicLink(c *gin.Context) {
+	var req models.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	if err := h.magicLinkService.SendMagicLink(req.Email); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.Success(c, true)
+}
+
+// 凭登录链接中的令牌登录，返回结果与 /users/login 相同
+func (h *MagicLinkHandler) Login(c *gin.Context) {
+	var req models.MagicLinkLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	user, err := h.magicLinkService.Redeem(req.Token)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	startLogin(c, h.tokenService, h.twoFactorService, user)
+}
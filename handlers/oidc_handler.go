@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/middleware"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+// 发起登录时写入 state 的 Cookie，只随回调请求发送
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/v1/users/oidc"
+	// 发起登录时要求 Cookie 会话
+	oidcCookieAuthPrefix = "cookie."
+)
+
+type OIDCHandler struct {
+	oidcService      *services.OIDCService
+	tokenService     *services.TokenService
+	twoFactorService *services.TwoFactorService
+	secureCookie     bool
+}
+
+func NewOIDCHandler(oidcService *services.OIDCService, tokenService *services.TokenService, twoFactorService *services.TwoFactorService, secureCookie bool) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService:      oidcService,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+		secureCookie:     secureCookie,
+	}
+}
+
+// 发起 SSO 登录：state 写入 HttpOnly Cookie，重定向到提供方的授权页面
+// 浏览器跳转无法携带 X-Auth-Mode 头，需要 Cookie 会话时使用 ?auth_mode=cookie，记录在 state Cookie 中
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, state, err := h.oidcService.AuthorizationURL()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	if strings.EqualFold(c.Query("auth_mode"), "cookie") {
+		state = oidcCookieAuthPrefix + state
+	}
+	h.setStateCookie(c, state, int(services.OIDCStateExpire.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// 提供方回调：换取 ID Token，登录关联的本地用户，返回与 /users/login 相同的结果
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	// 用户在提供方拒绝授权等
+	if errCode := c.Query("error"); errCode != "" {
+		utils.Error(c, http.StatusUnauthorized, "OIDC login failed: "+errCode)
+		return
+	}
+
+	browserState, _ := c.Cookie(oidcStateCookie)
+	h.setStateCookie(c, "", -1)
+	if state, ok := strings.CutPrefix(browserState, oidcCookieAuthPrefix); ok {
+		browserState = state
+		c.Request.Header.Set(middleware.AuthModeHeader, "cookie")
+	}
+	user, err := h.oidcService.Callback(c.Query("code"), c.Query("state"), browserState)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	startLogin(c, h.tokenService, h.twoFactorService, user)
+}
+
+// 回调由提供方重定向回来（跨站的顶级导航），SameSite 只能为 Lax
+func (h *OIDCHandler) setStateCookie(c *gin.Context, state string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     oidcStateCookiePath,
+		MaxAge:   maxAge, // 小于 0 时删除
+		Secure:   h.secureCookie,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
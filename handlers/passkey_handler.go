@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+type PasskeyHandler struct {
+	webAuthnService *services.WebAuthnService
+	tokenService    *services.TokenService
+}
+
+func NewPasskeyHandler(webAuthnService *services.WebAuthnService, tokenService *services.TokenService) *PasskeyHandler {
+	return &PasskeyHandler{
+		webAuthnService: webAuthnService,
+		tokenService:    tokenService,
+	}
+}
+
+// 注册通行密钥第一步：返回创建凭据的选项
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	options, err := h.webAuthnService.BeginRegistration(userID.(uint))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, options)
+}
+
+// 注册通行密钥第二步：提交认证器创建的凭据
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.FinishPasskeyRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	credential, err := h.webAuthnService.FinishRegistration(userID.(uint), req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, credential)
+}
+
+// 查询通行密钥
+func (h *PasskeyHandler) ListPasskeys(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	credentials, err := h.webAuthnService.ListPasskeys(userID.(uint))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, credentials)
+}
+
+// 删除通行密钥
+func (h *PasskeyHandler) DeletePasskey(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	if err := h.webAuthnService.DeletePasskey(userID.(uint), uint(uintid)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, true)
+}
+
+// 通行密钥登录第一步：返回获取断言的选项（请求体可选）
+func (h *PasskeyHandler) BeginLogin(c *gin.Context) {
+	var req models.BeginPasskeyLoginRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(c, utils.ParseValidationErrors(err))
+			return
+		}
+	}
+
+	options, err := h.webAuthnService.BeginLogin(req.Username)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, options)
+}
+
+// 通行密钥登录第二步：校验断言，返回结果与 /users/login 相同
+// 通行密钥要求用户验证，本身就是多因素认证，不再要求两步验证
+func (h *PasskeyHandler) FinishLogin(c *gin.Context) {
+	var req models.FinishPasskeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	user, err := h.webAuthnService.FinishLogin(req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	loginSuccess(c, h.tokenService, user)
+}
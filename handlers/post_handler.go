@@ -66,7 +66,7 @@ func (h *PostHandler) ListPost(c *gin.Context) {
 		return
 	}
 
-	posts, err := h.postService.ListPost(userID.(uint), pageNo, pageSize)
+	posts, err := h.postService.ListPost(userID.(uint), c.Query("status"), pageNo, pageSize)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
@@ -75,7 +75,7 @@ func (h *PostHandler) ListPost(c *gin.Context) {
 	utils.Success(c, posts)
 }
 
-// 查询所有用户的文章
+// 查询所有用户的文章：?q= 按查询语言筛选，如 q=title:gin author:admin comments:>=3
 func (h *PostHandler) ListPostAll(c *gin.Context) {
 	pageNoStr := c.DefaultQuery("pageNo", "1") // 带默认值
 	pageSizeStr := c.DefaultQuery("pageSize", "5")
@@ -90,7 +90,7 @@ func (h *PostHandler) ListPostAll(c *gin.Context) {
 		return
 	}
 
-	posts, err := h.postService.ListPostAll(pageNo, pageSize)
+	posts, err := h.postService.ListPostAll(viewerID(c), c.Query("q"), pageNo, pageSize)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
@@ -126,7 +126,7 @@ func (h *PostHandler) GetPostById(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postService.GetPostById(uint(uintid))
+	post, err := h.postService.GetPostById(uint(uintid), viewerID(c))
 	if err != nil {
 		utils.HandleError(c, err)
 		return
@@ -194,3 +194,11 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 
 	utils.Success(c, r)
 }
+
+// 当前登录用户 id，匿名访问时为 0
+func viewerID(c *gin.Context) uint {
+	if userID, exists := c.Get("userID"); exists {
+		return userID.(uint)
+	}
+	return 0
+}
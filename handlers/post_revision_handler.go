@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+// 文章修订：作者查看、比较、回滚自己文章的修订，版主查看任意文章的修订
+type PostRevisionHandler struct {
+	revisionService *services.PostRevisionService
+}
+
+func NewPostRevisionHandler(revisionService *services.PostRevisionService) *PostRevisionHandler {
+	return &PostRevisionHandler{revisionService: revisionService}
+}
+
+// 查询文章的修订历史
+func (h *PostRevisionHandler) ListRevisions(c *gin.Context) {
+	h.listRevisions(c, false)
+}
+
+// 查询任意文章的修订历史（管理后台）
+func (h *PostRevisionHandler) AdminListRevisions(c *gin.Context) {
+	h.listRevisions(c, true)
+}
+
+// 查询文章的某个修订
+func (h *PostRevisionHandler) GetRevision(c *gin.Context) {
+	h.getRevision(c, false)
+}
+
+// 查询任意文章的某个修订（管理后台）
+func (h *PostRevisionHandler) AdminGetRevision(c *gin.Context) {
+	h.getRevision(c, true)
+}
+
+// 比较文章的两个修订：?from=1&to=2
+func (h *PostRevisionHandler) DiffRevisions(c *gin.Context) {
+	h.diffRevisions(c, false)
+}
+
+// 比较任意文章的两个修订（管理后台）
+func (h *PostRevisionHandler) AdminDiffRevisions(c *gin.Context) {
+	h.diffRevisions(c, true)
+}
+
+// 回滚到旧修订，回滚本身保存为新的修订
+func (h *PostRevisionHandler) RestoreRevision(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	postID, ok := parseUintParam(c, c.Param("id"), "Invalid id")
+	if !ok {
+		return
+	}
+	version, ok := parseUintParam(c, c.Param("version"), "Invalid version")
+	if !ok {
+		return
+	}
+
+	post, err := h.revisionService.RestoreRevision(userID.(uint), postID, version)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, post)
+}
+
+func (h *PostRevisionHandler) listRevisions(c *gin.Context, all bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	postID, ok := parseUintParam(c, c.Param("id"), "Invalid id")
+	if !ok {
+		return
+	}
+
+	pageNo, pageSize := utils.GetQueryPage(c)
+	revisions, err := h.revisionService.ListRevisions(userID.(uint), postID, all, pageNo, pageSize)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, revisions)
+}
+
+func (h *PostRevisionHandler) getRevision(c *gin.Context, all bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	postID, ok := parseUintParam(c, c.Param("id"), "Invalid id")
+	if !ok {
+		return
+	}
+	version, ok := parseUintParam(c, c.Param("version"), "Invalid version")
+	if !ok {
+		return
+	}
+
+	revision, err := h.revisionService.GetRevision(userID.(uint), postID, version, all)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, revision)
+}
+
+func (h *PostRevisionHandler) diffRevisions(c *gin.Context, all bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	postID, ok := parseUintParam(c, c.Param("id"), "Invalid id")
+	if !ok {
+		return
+	}
+	from, ok := parseUintParam(c, c.Query("from"), "Invalid version")
+	if !ok {
+		return
+	}
+	to, ok := parseUintParam(c, c.Query("to"), "Invalid version")
+	if !ok {
+		return
+	}
+
+	diff, err := h.revisionService.DiffRevisions(userID.(uint), postID, from, to, all)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, diff)
+}
+
+// 解析 uint 参数，失败时返回 409
+func parseUintParam(c *gin.Context, value, message string) (uint, bool) {
+	uintid, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, message))
+		return 0, false
+	}
+	return uint(uintid), true
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+// 全文搜索
+type SearchHandler struct {
+	searchService *services.SearchService
+}
+
+func NewSearchHandler(searchService *services.SearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+
+// 搜索文章或评论：?q=关键词&type=post|comment
+func (h *SearchHandler) Search(c *gin.Context) {
+	pageNo, pageSize := utils.GetQueryPage(c)
+	result, err := h.searchService.Search(c.Query("q"), c.DefaultQuery("type", models.SearchTypePost), pageNo, pageSize)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, result)
+}
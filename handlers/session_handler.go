@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+}
+
+func NewSessionHandler(sessionService *services.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+// 查询登录会话（设备）
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessions, err := h.sessionService.ListSessions(claims.(*utils.Claims).UserID, claims.(*utils.Claims).SessionID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, sessions)
+}
+
+// 撤销登录会话：该设备需要重新登录
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	if err := h.sessionService.RevokeSession(userID.(uint), uint(uintid)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, true)
+}
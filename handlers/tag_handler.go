@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+// 标签
+type TagHandler struct {
+	tagService *services.TagService
+}
+
+func NewTagHandler(tagService *services.TagService) *TagHandler {
+	return &TagHandler{tagService: tagService}
+}
+
+// 查询标签及文章数：?q=go 按前缀过滤
+func (h *TagHandler) ListTags(c *gin.Context) {
+	pageNo, pageSize := utils.GetQueryPage(c)
+	tags, err := h.tagService.ListTags(c.Query("q"), pageNo, pageSize)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, tags)
+}
@@ -1,24 +1,33 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"gin-examples/project/middleware"
 	"gin-examples/project/models"
 	"gin-examples/project/services"
 	"gin-examples/project/utils"
 )
 
 type UserHandler struct {
-	userService *services.UserService
-	jwtSecret   []byte
+	userService         *services.UserService
+	tokenService        *services.TokenService
+	verificationService *services.VerificationService
+	twoFactorService    *services.TwoFactorService
 }
 
-func NewUserHandler(userService *services.UserService, jwtSecret []byte) *UserHandler {
+func NewUserHandler(userService *services.UserService, tokenService *services.TokenService, verificationService *services.VerificationService, twoFactorService *services.TwoFactorService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		jwtSecret:   jwtSecret,
+		userService:         userService,
+		tokenService:        tokenService,
+		verificationService: verificationService,
+		twoFactorService:    twoFactorService,
 	}
 }
 
@@ -35,12 +44,12 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	utils.Success(c, models.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-	})
+	// 发送验证邮件失败不影响注册，用户可以重新发送
+	if err := h.verificationService.SendVerification(user); err != nil {
+		log.Printf("send verification mail to %s failed: %v", user.Email, err)
+	}
+
+	utils.Success(c, newUserResponse(user))
 }
 
 func (h *UserHandler) Login(c *gin.Context) {
@@ -50,30 +59,169 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.Authenticate(req.Username, req.Password)
+	user, err := h.userService.Authenticate(req.Username, req.Password, c.ClientIP())
+	if err != nil {
+		handleLoginError(c, err)
+		return
+	}
+
+	startLogin(c, h.tokenService, h.twoFactorService, user)
+}
+
+// 两步验证登录第二步：挑战令牌 + 验证码（或恢复码）
+func (h *UserHandler) LoginTwoFactor(c *gin.Context) {
+	var req models.LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	user, err := h.twoFactorService.CompleteLogin(req.ChallengeToken, req.Code, c.ClientIP())
+	if err != nil {
+		handleLoginError(c, err)
+		return
+	}
+
+	loginSuccess(c, h.tokenService, user)
+}
+
+// 身份已确认（密码、SSO 等）：开启了两步验证时只返回挑战令牌，由 /users/login/2fa 完成登录
+func startLogin(c *gin.Context, tokenService *services.TokenService, twoFactorService *services.TwoFactorService, user *models.User) {
+	enabled, err := twoFactorService.IsEnabled(user.ID)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
+	if enabled {
+		challenge, err := twoFactorService.IssueChallenge(user)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+		utils.Success(c, challenge)
+		return
+	}
+
+	loginSuccess(c, tokenService, user)
+}
 
-	token, err := utils.GenerateToken(h.jwtSecret, user.ID, user.Username)
+// 签发令牌并返回登录结果
+func loginSuccess(c *gin.Context, tokenService *services.TokenService, user *models.User) {
+	pair, err := tokenService.IssueTokens(user, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
 
+	// 浏览器客户端要求 Cookie 会话：令牌只写入 Cookie，不在响应体中返回
+	if middleware.CookieAuthRequested(c) {
+		csrfToken, err := middleware.SetSessionCookies(c, pair.AccessToken, pair.RefreshToken, time.Duration(pair.ExpiresIn)*time.Second)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+		utils.Success(c, gin.H{
+			"csrf_token": csrfToken,
+			"expires_in": pair.ExpiresIn,
+			"user":       newUserResponse(user),
+		})
+		return
+	}
+
 	utils.Success(c, gin.H{
-		"token": token,
-		"user": models.UserResponse{
-			ID:         user.ID,
-			Username:   user.Username,
-			Email:      user.Email,
-			PostNumber: user.PostNumber,
-			CreatedAt:  user.CreatedAt,
-		},
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+		"user":          newUserResponse(user),
 	})
 }
 
+// 刷新令牌：轮换刷新令牌并签发新的访问令牌
+// Cookie 会话模式下请求体可省略，从 Cookie 读取刷新令牌，新令牌同样写入 Cookie
+func (h *UserHandler) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	cookieAuth := middleware.CookieAuthRequested(c)
+	if middleware.CookieSessionEnabled(c) && c.Request.ContentLength <= 0 {
+		cookieAuth = true
+		req.RefreshToken = middleware.SessionRefreshToken(c)
+		if req.RefreshToken == "" {
+			utils.Error(c, http.StatusUnauthorized, "Refresh token required")
+			return
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	pair, err := h.tokenService.RefreshTokens(req.RefreshToken)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	if cookieAuth {
+		csrfToken, err := middleware.SetSessionCookies(c, pair.AccessToken, pair.RefreshToken, time.Duration(pair.ExpiresIn)*time.Second)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+		utils.Success(c, gin.H{
+			"csrf_token": csrfToken,
+			"expires_in": pair.ExpiresIn,
+		})
+		return
+	}
+
+	utils.Success(c, pair)
+}
+
+// 退出登录：注销当前访问令牌
+func (h *UserHandler) Logout(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// 请求体可选
+	var req models.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(c, utils.ParseValidationErrors(err))
+			return
+		}
+	}
+
+	if req.RefreshToken == "" {
+		req.RefreshToken = middleware.SessionRefreshToken(c)
+	}
+
+	if err := h.tokenService.Logout(claims.(*utils.Claims), req.RefreshToken); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	middleware.ClearSessionCookies(c)
+	utils.Success(c, true)
+}
+
+// 退出所有设备：已签发的令牌全部失效
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.tokenService.LogoutAll(userID.(uint)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	middleware.ClearSessionCookies(c)
+	utils.Success(c, true)
+}
+
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -87,13 +235,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	utils.Success(c, models.UserResponse{
-		ID:         user.ID,
-		Username:   user.Username,
-		Email:      user.Email,
-		PostNumber: user.PostNumber,
-		CreatedAt:  user.CreatedAt,
-	})
+	utils.Success(c, newUserResponse(user))
 }
 
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
@@ -115,12 +257,48 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	utils.Success(c, models.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-	})
+	// 修改邮箱后需要重新验证
+	if req.Email != "" && user.EmailVerifiedAt == nil {
+		if err := h.verificationService.SendVerification(user); err != nil {
+			log.Printf("send verification mail to %s failed: %v", user.Email, err)
+		}
+	}
+
+	utils.Success(c, newUserResponse(user))
+}
+
+// 修改密码：此前签发的令牌全部失效，为当前设备返回新的令牌
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, utils.ParseValidationErrors(err))
+		return
+	}
+
+	user, err := h.userService.ChangePassword(userID.(uint), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	loginSuccess(c, h.tokenService, user)
+}
+
+// 公开资料：任何人可查看，不包含邮箱等私有字段
+func (h *UserHandler) GetPublicProfile(c *gin.Context) {
+	pageNo, pageSize := utils.GetQueryPage(c)
+	profile, err := h.userService.GetPublicProfile(c.Param("username"), pageNo, pageSize)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, profile)
 }
 
 func (h *UserHandler) StatisticPostAuditStatus(c *gin.Context) {
@@ -131,3 +309,29 @@ func (h *UserHandler) StatisticPostAuditStatus(c *gin.Context) {
 	}
 	utils.Success(c, sta)
 }
+
+// 登录失败：被锁定时设置 Retry-After 头
+func handleLoginError(c *gin.Context, err error) {
+	var appErr *utils.AppError
+	if errors.As(err, &appErr) {
+		if notice, ok := appErr.Details.(services.LoginLockNotice); ok {
+			c.Header("Retry-After", strconv.FormatInt(notice.RetryAfter, 10))
+		}
+	}
+	utils.HandleError(c, err)
+}
+
+// 用户信息响应
+func newUserResponse(user *models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		PostNumber:    user.PostNumber,
+		CreatedAt:     user.CreatedAt,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		InvitedBy:     user.InvitedByID,
+		ProfileFields: services.NewProfileFields(user),
+	}
+}
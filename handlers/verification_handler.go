@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+)
+
+type VerificationHandler struct {
+	verificationService *services.VerificationService
+}
+
+func NewVerificationHandler(verificationService *services.VerificationService) *VerificationHandler {
+	return &VerificationHandler{
+		verificationService: verificationService,
+	}
+}
+
+// 点击邮件中的链接验证邮箱
+func (h *VerificationHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		utils.Error(c, http.StatusBadRequest, "token required")
+		return
+	}
+
+	user, err := h.verificationService.VerifyEmail(token)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.Success(c, newUserResponse(user))
+}
+
+// 重新发送验证邮件（登录用户）
+func (h *VerificationHandler) ResendVerification(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.verificationService.ResendVerification(userID.(uint)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.Success(c, true)
+}
+
+// 管理员：重新发送验证邮件
+func (h *VerificationHandler) AdminResendVerification(c *gin.Context) {
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	if err := h.verificationService.ResendVerification(uint(uintid)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.Success(c, true)
+}
+
+// 管理员：直接标记邮箱已验证
+func (h *VerificationHandler) AdminForceVerify(c *gin.Context) {
+	id := c.Param("id")
+	uintid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		fmt.Println("主键id字符串转 uint64 转换错误:", err)
+		utils.HandleError(c, utils.NewAppError(409, "Invalid id"))
+		return
+	}
+
+	user, err := h.verificationService.ForceVerify(uint(uintid))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.Success(c, newUserResponse(user))
+}
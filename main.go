@@ -8,6 +8,7 @@ import (
 	"gin-examples/project/config"
 	"gin-examples/project/models"
 	"gin-examples/project/router"
+	"gin-examples/project/services"
 )
 
 func main() {
@@ -27,10 +28,31 @@ func main() {
 	- **不会删除索引**
 	- 追加 uniqueIndex 时，若是数据中存在重复的数据，那么启动失败。
 	*/
-	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}); err != nil {
+	// 旧版本没有邮箱验证：新增该列后，已有用户视为已验证
+	grandfatherEmails := db.Migrator().HasTable(&models.User{}) && !db.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.Role{}, &models.Permission{}, &models.SigningKey{}, &models.PasswordResetToken{}, &models.LoginAttempt{}, &models.TwoFactor{}, &models.RecoveryCode{}, &models.PersonalAccessToken{}, &models.UserIdentity{}, &models.OIDCLoginState{}, &models.Session{}, &models.MagicLinkToken{}, &models.WebAuthnCredential{}, &models.WebAuthnChallenge{}, &models.Invite{}, &models.InviteRedemption{}, &models.PostRevision{}, &models.Tag{}, &models.Category{}); err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	if grandfatherEmails {
+		count, err := services.GrandfatherVerifiedEmails(db)
+		if err != nil {
+			log.Fatalf("Failed to migrate email verification: %v", err)
+		}
+		log.Printf("Marked %d existing users as email verified", count)
+	}
+
+	// 初始化内置角色和权限
+	if err := services.NewRoleService(db).SeedDefaultRoles(); err != nil {
+		log.Fatalf("Failed to seed roles: %v", err)
+	}
+	// 初始化第一个管理员（用户需先注册）
+	if promoted, err := services.NewRoleService(db).BootstrapAdmin(cfg.Auth.BootstrapAdmin); err != nil {
+		log.Printf("Warning: failed to bootstrap admin %q: %v", cfg.Auth.BootstrapAdmin, err)
+	} else if promoted {
+		log.Printf("User %q is now admin", cfg.Auth.BootstrapAdmin)
+	}
+
 	// 定义路由
 	r := router.SetupRouter(cfg, db)
 
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -9,39 +10,131 @@ import (
 	"gin-examples/project/utils"
 )
 
-func Auth(jwtSecret []byte) gin.HandlerFunc {
+// 访问令牌校验（由 services.TokenService 实现）
+type TokenValidator interface {
+	ValidateAccessToken(tokenString string) (*utils.Claims, error)
+}
+
+// 登录认证：只接受登录签发的访问令牌，个人访问令牌被拒绝
+func Auth(validator TokenValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 从 Header 获取 Token
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			utils.Error(c, http.StatusUnauthorized, "Authorization header required")
+		claims, ok := authenticate(c, validator)
+		if !ok {
+			return
+		}
+		if claims.TokenType == utils.TokenTypePersonalAccess {
+			utils.Error(c, http.StatusForbidden, "Personal access token not allowed")
 			c.Abort()
 			return
 		}
 
-		// 提取 Token（Bearer <token>）
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			utils.Error(c, http.StatusUnauthorized, "Invalid authorization header format")
+		c.Next()
+	}
+}
+
+// 登录认证，同时接受拥有指定 scope 的个人访问令牌（登录令牌不受 scope 限制）
+func AuthWithScope(validator TokenValidator, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := authenticate(c, validator)
+		if !ok {
+			return
+		}
+		if claims.TokenType == utils.TokenTypePersonalAccess && !slices.Contains(claims.Scopes, scope) {
+			utils.Error(c, http.StatusForbidden, "Insufficient scope: "+scope)
 			c.Abort()
 			return
 		}
 
-		tokenString := parts[1]
+		c.Next()
+	}
+}
+
+// 可选登录：没有 Authorization 头时按匿名用户继续处理，令牌无效时拒绝。
+// 缺少 scope 的个人访问令牌同样按匿名用户处理：公开内容本就可以匿名访问，scope 只用于访问自己未发布的内容
+func OptionalAuth(validator TokenValidator, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		claims, ok := validateAuthorization(c, validator)
+		if !ok {
+			return
+		}
+		if claims.TokenType != utils.TokenTypePersonalAccess || slices.Contains(claims.Scopes, scope) {
+			setClaims(c, claims)
+		}
 
-		// 验证 Token
-		claims, err := utils.ParseToken(tokenString, jwtSecret)
-		if err != nil {
-			utils.Error(c, http.StatusUnauthorized, "Invalid token")
+		c.Next()
+	}
+}
+
+// 校验 Authorization 头中的令牌，并将用户信息存储到 Context
+func authenticate(c *gin.Context, validator TokenValidator) (*utils.Claims, bool) {
+	claims, ok := validateAuthorization(c, validator)
+	if !ok {
+		return nil, false
+	}
+	setClaims(c, claims)
+	return claims, true
+}
+
+// 校验 Authorization 头中的令牌，失败时返回错误响应
+func validateAuthorization(c *gin.Context, validator TokenValidator) (*utils.Claims, bool) {
+	// 从 Header 获取 Token
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		utils.Error(c, http.StatusUnauthorized, "Authorization header required")
+		c.Abort()
+		return nil, false
+	}
+
+	// 提取 Token（Bearer <token>）
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		utils.Error(c, http.StatusUnauthorized, "Invalid authorization header format")
+		c.Abort()
+		return nil, false
+	}
+
+	tokenString := parts[1]
+
+	// 验证 Token（签名、有效期、签发者、受众、是否已注销）
+	claims, err := validator.ValidateAccessToken(tokenString)
+	if err != nil {
+		utils.HandleError(c, err)
+		c.Abort()
+		return nil, false
+	}
+	return claims, true
+}
+
+// 将用户信息存储到 Context
+func setClaims(c *gin.Context, claims *utils.Claims) {
+	c.Set("userID", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("claims", claims)
+}
+
+// 权限校验：必须在 Auth 之后使用，要求令牌拥有全部指定权限
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("claims")
+		if !exists {
+			utils.Error(c, http.StatusUnauthorized, "Unauthorized")
 			c.Abort()
 			return
 		}
+		claims := value.(*utils.Claims)
 
-		// 将用户信息存储到 Context
-		c.Set("userID", claims.UserID)
-		c.Set("username", claims.Username)
+		for _, required := range permissions {
+			if !slices.Contains(claims.Permissions, required) {
+				utils.Error(c, http.StatusForbidden, "Permission denied: "+required)
+				c.Abort()
+				return
+			}
+		}
 
 		c.Next()
 	}
 }
-
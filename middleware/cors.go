@@ -2,18 +2,26 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CORS() gin.HandlerFunc {
+// 跨域：任意来源都可以不携带凭证访问（Authorization 头中的令牌不会被浏览器自动发送）；
+// 只有 credentialOrigins 中的来源可以携带凭证（Cookie）访问
+func CORS(credentialOrigins []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
+		c.Header("Vary", "Origin")
 		if origin != "" {
-			c.Header("Access-Control-Allow-Origin", origin)
+			if slices.Contains(credentialOrigins, origin) {
+				c.Header("Access-Control-Allow-Origin", origin)
+				c.Header("Access-Control-Allow-Credentials", "true")
+			} else {
+				c.Header("Access-Control-Allow-Origin", "*")
+			}
 			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			c.Header("Access-Control-Allow-Credentials", "true")
+			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token, X-Auth-Mode")
 		}
 
 		if c.Request.Method == "OPTIONS" {
@@ -24,4 +32,3 @@ func CORS() gin.HandlerFunc {
 		c.Next()
 	}
 }
-
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-examples/project/utils"
+)
+
+// Cookie 会话：浏览器客户端使用的 Cookie 和请求头名称
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+	CSRFTokenCookie    = "csrf_token"
+	CSRFTokenHeader    = "X-CSRF-Token"
+	AuthModeHeader     = "X-Auth-Mode" // 浏览器客户端登录、刷新令牌时设为 cookie，令牌写入 Cookie
+)
+
+// Cookie 会话参数
+type SessionCookieOptions struct {
+	Domain        string
+	Secure        bool
+	SameSite      http.SameSite
+	RefreshPath   string        // 刷新令牌 Cookie 的路径，只随刷新、退出等请求发送
+	RefreshMaxAge time.Duration // 刷新令牌有效期
+}
+
+// Cookie 会话模式：登录时令牌写入 HttpOnly Cookie，请求未携带 Authorization 头时从 Cookie 读取访问令牌
+// 使用 Cookie 凭证的非安全请求（POST/PUT/PATCH/DELETE）必须通过双重提交校验：X-CSRF-Token 头与 csrf_token Cookie 一致
+// 携带 Authorization 头的请求（API 客户端）不受影响
+func CookieSession(options *SessionCookieOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("sessionCookies", options)
+
+		if c.GetHeader("Authorization") != "" {
+			c.Next()
+			return
+		}
+
+		accessToken, _ := c.Cookie(AccessTokenCookie)
+		refreshToken, _ := c.Cookie(RefreshTokenCookie)
+		if accessToken == "" && refreshToken == "" {
+			c.Next()
+			return
+		}
+
+		if !isSafeMethod(c.Request.Method) && !validCSRFToken(c) {
+			utils.Error(c, http.StatusForbidden, "Invalid CSRF token")
+			c.Abort()
+			return
+		}
+
+		// 交给 Auth 按 Bearer 令牌校验
+		if accessToken != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		c.Next()
+	}
+}
+
+// 是否启用了 Cookie 会话模式
+func CookieSessionEnabled(c *gin.Context) bool {
+	return sessionCookieOptions(c) != nil
+}
+
+// 本次请求是否要求以 Cookie 返回令牌（X-Auth-Mode: cookie）。
+// 同一服务同时面向浏览器和 API 客户端，由客户端逐个请求选择，未指定时令牌在响应体中返回
+func CookieAuthRequested(c *gin.Context) bool {
+	return CookieSessionEnabled(c) && strings.EqualFold(c.GetHeader(AuthModeHeader), "cookie")
+}
+
+// 写入会话 Cookie，返回新的 CSRF 令牌；未启用 Cookie 会话模式时不做任何处理
+func SetSessionCookies(c *gin.Context, accessToken, refreshToken string, accessMaxAge time.Duration) (string, error) {
+	options := sessionCookieOptions(c)
+	if options == nil {
+		return "", nil
+	}
+
+	csrfToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	setCookie(c, options, AccessTokenCookie, accessToken, "/", accessMaxAge, true)
+	setCookie(c, options, RefreshTokenCookie, refreshToken, options.RefreshPath, options.RefreshMaxAge, true)
+	// CSRF 令牌需要被前端脚本读取，不能设置 HttpOnly；有效期与刷新令牌一致
+	setCookie(c, options, CSRFTokenCookie, csrfToken, "/", options.RefreshMaxAge, false)
+	return csrfToken, nil
+}
+
+// 清除会话 Cookie（退出登录）
+func ClearSessionCookies(c *gin.Context) {
+	options := sessionCookieOptions(c)
+	if options == nil {
+		return
+	}
+
+	setCookie(c, options, AccessTokenCookie, "", "/", -1, true)
+	setCookie(c, options, RefreshTokenCookie, "", options.RefreshPath, -1, true)
+	setCookie(c, options, CSRFTokenCookie, "", "/", -1, false)
+}
+
+// 从 Cookie 读取刷新令牌
+func SessionRefreshToken(c *gin.Context) string {
+	if sessionCookieOptions(c) == nil {
+		return ""
+	}
+	token, _ := c.Cookie(RefreshTokenCookie)
+	return token
+}
+
+func sessionCookieOptions(c *gin.Context) *SessionCookieOptions {
+	value, exists := c.Get("sessionCookies")
+	if !exists {
+		return nil
+	}
+	options, _ := value.(*SessionCookieOptions)
+	return options
+}
+
+// maxAge 小于 0 时删除 Cookie
+func setCookie(c *gin.Context, options *SessionCookieOptions, name, value, path string, maxAge time.Duration, httpOnly bool) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   options.Domain,
+		MaxAge:   int(maxAge / time.Second),
+		Secure:   options.Secure,
+		HttpOnly: httpOnly,
+		SameSite: options.SameSite,
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(c.Writer, cookie)
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// 双重提交校验：请求头与 Cookie 中的 CSRF 令牌一致
+func validCSRFToken(c *gin.Context) bool {
+	cookie, _ := c.Cookie(CSRFTokenCookie)
+	header := c.GetHeader(CSRFTokenHeader)
+	if cookie == "" || header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
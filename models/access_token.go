@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// 个人访问令牌的授权范围
+const (
+	ScopePostsRead     = "posts:read"     // 查询自己的文章
+	ScopePostsWrite    = "posts:write"    // 发表、修改、删除文章
+	ScopeCommentsRead  = "comments:read"  // 查询评论
+	ScopeCommentsWrite = "comments:write" // 发表、删除评论
+	ScopeProfileRead   = "profile:read"   // 查询登录用户信息
+)
+
+var AccessTokenScopes = []string{ScopePostsRead, ScopePostsWrite, ScopeCommentsRead, ScopeCommentsWrite, ScopeProfileRead}
+
+// 个人访问令牌：供脚本、CI 调用接口，只保存摘要
+type PersonalAccessToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"index;not null"`
+	Name       string     `json:"name" gorm:"not null;size:100"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	Prefix     string     `json:"prefix" gorm:"size:16"` // 令牌前几位，便于用户辨认
+	Scopes     []string   `json:"scopes" gorm:"serializer:json;type:text"`
+	ExpiresAt  *time.Time `json:"expires_at"` // 为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // 为空表示永不过期
+}
+
+// 创建令牌的响应：明文令牌只返回这一次
+type CreateAccessTokenResponse struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"gin-examples/project/utils"
+)
+
+// 注销账号时用户内容的处理方式
+const (
+	AccountDeletionAnonymize = "anonymize" // 保留文章和评论，抹去用户的个人信息
+	AccountDeletionDelete    = "delete"    // 物理删除用户及其文章、评论
+)
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"` // 为空时要求当前会话是最近 10 分钟内登录的
+	Code     string `json:"code"`     // 开启了两步验证时必填：验证码或恢复码
+}
+
+// 个人数据导出：profile.json
+type AccountExportProfile struct {
+	ID              uint       `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Role            string     `json:"role"`
+	PostNumber      uint       `json:"post_number"`
+	InvitedBy       *uint      `json:"invited_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	ExportedAt      time.Time  `json:"exported_at"`
+	ProfileFields
+}
+
+// 个人数据导出：posts.json，包含审计状态
+type AccountExportPost struct {
+	ID            uint        `json:"id"`
+	Title         string      `json:"title"`
+	Content       string      `json:"content"`
+	CommentNumber uint        `json:"comment_number"`
+	CommentStatus string      `json:"comment_status"`
+	Status        string      `json:"status"`
+	PublishAt     *time.Time  `json:"publish_at"`
+	PublishedAt   *time.Time  `json:"published_at"`
+	AuditBy       string      `json:"audit_by"`
+	AuditVersion  string      `json:"audit_version"`
+	AuditStatus   string      `json:"audit_status"`
+	CreatedAt     utils.Time1 `json:"created_at"`
+	UpdatedAt     utils.Time1 `json:"updated_at"`
+}
+
+// 个人数据导出：comments.json
+type AccountExportComment struct {
+	ID        uint      `json:"id"`
+	PostID    uint      `json:"post_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 个人数据导出：audit.json，登录会话、外部身份、令牌、通行密钥、邀请等安全记录
+type AccountExportAudit struct {
+	TwoFactorEnabledAt *time.Time            `json:"two_factor_enabled_at"`
+	Sessions           []Session             `json:"sessions"`
+	Identities         []UserIdentity        `json:"identities"`
+	AccessTokens       []PersonalAccessToken `json:"access_tokens"`
+	Passkeys           []WebAuthnCredential  `json:"passkeys"`
+	Invites            []Invite              `json:"invites"`
+	InviteRedemption   *InviteRedemption     `json:"invite_redemption"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// 文章分类：树形结构，Path 为从根到自身的 id 路径（如 /1/4/），用于查询子树
+type Category struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null;size:50"`
+	Slug      string    `json:"slug" gorm:"uniqueIndex;not null;size:50"`
+	ParentID  *uint     `json:"parent_id" gorm:"index"`
+	Path      string    `json:"-" gorm:"index;not null;size:255"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 分类最大层级
+const MaxCategoryDepth = 5
+
+type CreateCategoryRequest struct {
+	Name     string `json:"name" binding:"required,max=50"`
+	Slug     string `json:"slug" binding:"required,max=50"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type UpdateCategoryRequest struct {
+	Name     string `json:"name" binding:"required,max=50"`
+	Slug     string `json:"slug" binding:"required,max=50"`
+	ParentID *uint  `json:"parent_id"` // 为空表示移到根分类下
+}
+
+// 分类树：PostCount 为分类及其全部子分类下已发布的文章数
+type CategoryNode struct {
+	ID        uint            `json:"id"`
+	Name      string          `json:"name"`
+	Slug      string          `json:"slug"`
+	PostCount int64           `json:"post_count"`
+	Children  []*CategoryNode `json:"children"`
+}
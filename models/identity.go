@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// 外部身份：OIDC 提供方的账号（issuer + sub）关联到本地用户
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	Issuer    string    `json:"issuer" gorm:"uniqueIndex:idx_identity_subject;not null;size:191"`
+	Subject   string    `json:"subject" gorm:"uniqueIndex:idx_identity_subject;not null;size:191"`
+	Email     string    `json:"email" gorm:"size:100"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OIDC 登录发起时保存的 state、nonce 和 PKCE code_verifier，回调时一次性使用
+type OIDCLoginState struct {
+	State        string    `gorm:"primaryKey;size:64"`
+	Nonce        string    `gorm:"not null;size:64"`
+	CodeVerifier string    `gorm:"not null;size:128"`
+	ExpiresAt    time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
+
+// 默认表名会被拆成 o_id_c_login_states
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
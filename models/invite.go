@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// 邀请码：可使用 MaxUses 次，过期或撤销后失效
+type Invite struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Code        string     `json:"code" gorm:"uniqueIndex;not null;size:32"`
+	CreatedByID uint       `json:"created_by" gorm:"index;not null"`
+	MaxUses     int        `json:"max_uses" gorm:"not null"`
+	UsedCount   int        `json:"used_count" gorm:"not null;default:0"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// 邀请码使用记录
+type InviteRedemption struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	InviteID  uint      `json:"invite_id" gorm:"index;not null"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateInviteRequest struct {
+	MaxUses       int `json:"max_uses" binding:"omitempty,min=1,max=1000"`       // 默认 1
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // 只有管理员可以指定，默认 auth.registration.invite_expire
+}
+
+type InviteListResponse struct {
+	Quota     int      `json:"quota"`     // 可邀请人数，-1 表示不限
+	Remaining int      `json:"remaining"` // 剩余可邀请人数，-1 表示不限
+	Invites   []Invite `json:"invites"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// 登录失败记录：按用户名、客户端 IP 分别统计
+type LoginAttempt struct {
+	Key           string    `gorm:"primaryKey;size:191"` // user:<用户名> 或 ip:<客户端 IP>
+	Failures      int       `gorm:"not null;default:0"`
+	LastFailureAt time.Time // 最近一次失败时间，用于计算递增延迟
+	LockedUntil   time.Time // 锁定截止时间
+	UpdatedAt     time.Time
+}
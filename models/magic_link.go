@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// 免密登录链接令牌：只保存摘要，一次性使用，过期失效
+type MagicLinkToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	Email     string     `json:"email" gorm:"size:100"` // 收件邮箱，登录时仍与用户邮箱一致才完成邮箱验证
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
@@ -22,9 +22,15 @@ type Post struct {
 	UserID uint // Foreign key to user
 	// Title         string           `json:"title" gorm:"not null;size:50;uniqueIndex"` // 追加 uniqueIndex 时，若是数据中存在重复的数据，那么启动失败。
 	Title         string           `json:"title" gorm:"not null;size:50"` // 加上的索引，要想去掉，只能手动去掉（删除数据库文件，重新创建）
-	Content       string           `json:"content" gorm:"not null;size:100"`
+	Content       string           `json:"content" gorm:"not null;type:mediumtext"`
 	CommentNumber uint             `json:"comment_number" gorm:"default:0"`
 	CommentStatus string           `json:"comment_status"`
+	Status        string           `json:"status" gorm:"size:20;not null;default:published;index"` // 发布状态，见 PostStatus*
+	PublishAt     *time.Time       `json:"publish_at" gorm:"index"`                                // 定时发布时间（scheduled）
+	PublishedAt   *time.Time       `json:"published_at"`                                           // 实际发布时间
+	CategoryID    *uint            `json:"category_id" gorm:"index"`
+	Category      *Category        `json:"category,omitempty"`
+	Tags          []Tag            `json:"tags" gorm:"many2many:post_tags"`
 	CreatedAt     utils.Time1      `json:"created_at"`
 	UpdatedAt     utils.Time1      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt   `json:"-" gorm:"index"`
@@ -33,15 +39,32 @@ type Post struct {
 	Audit         auditInputFields `json:"-" gorm:"embedded"`
 }
 
+// 文章发布状态：只有 published 对所有人可见，其他状态只有作者可见
+const (
+	PostStatusDraft     = "draft"     // 草稿
+	PostStatusScheduled = "scheduled" // 定时发布，到 PublishAt 时由调度器发布
+	PostStatusPublished = "published" // 已发布
+	PostStatusArchived  = "archived"  // 已归档
+)
+
 type CreatePostRequest struct {
-	Title   string `json:"title" gorm:"not null;size:50"`
-	Content string `json:"content" gorm:"not null;size:100"`
+	Title      string     `json:"title" gorm:"not null;size:50"`
+	Content    string     `json:"content" gorm:"not null;size:100" binding:"max=100000"`      // 最多 100000 字
+	Status     string     `json:"status" binding:"omitempty,oneof=draft scheduled published"` // 默认 published；指定了 publish_at 时默认 scheduled
+	PublishAt  *time.Time `json:"publish_at"`                                                 // 定时发布时间，必须晚于当前时间
+	Tags       []string   `json:"tags" binding:"omitempty,max=10,dive,required,max=30"`
+	CategoryID *uint      `json:"category_id"`
 }
 
 type UpdatePostRequest struct {
-	ID      uint   `json:"id" gorm:"primaryKey"`
-	Title   string `json:"title" gorm:"not null;size:50"`
-	Content string `json:"content" gorm:"not null;size:100"`
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Title      string     `json:"title" gorm:"not null;size:50"`
+	Content    string     `json:"content" gorm:"not null;size:100" binding:"max=100000"`               // 最多 100000 字
+	Status     string     `json:"status" binding:"omitempty,oneof=draft scheduled published archived"` // 为空表示不修改状态
+	PublishAt  *time.Time `json:"publish_at"`                                                          // status 为 scheduled 时必填
+	Note       string     `json:"note" binding:"max=200"`                                              // 修改说明，保存在修订中
+	Tags       []string   `json:"tags" binding:"omitempty,max=10,dive,required,max=30"`                // 不传表示不修改，[] 表示清空
+	CategoryID *uint      `json:"category_id"`                                                         // 不传表示不修改，0 表示取消分类
 }
 
 type ListPostRequest struct {
@@ -52,6 +75,9 @@ type ListPostRequest struct {
 	MinCommentNumber *uint      `json:"min_comment_number"`
 	CreatedAtStart   *time.Time `json:"created_at_start"`
 	CreatedAtEnd     *time.Time `json:"created_at_end"`
+	TagsAny          []string   `json:"tags_any" binding:"omitempty,max=10"` // 包含其中任意一个标签
+	TagsAll          []string   `json:"tags_all" binding:"omitempty,max=10"` // 包含全部标签
+	CategoryID       *uint      `json:"category_id"`                         // 分类及其子分类
 }
 
 type PagePostResponse struct {
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gin-examples/project/utils"
+
+	"gorm.io/gorm"
+)
+
+// 文章修订：每次创建、更新、回滚文章时保存一份标题和内容的快照，保存后不可修改
+type PostRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PostID    uint      `json:"post_id" gorm:"not null;uniqueIndex:idx_post_revisions_version"`
+	Version   uint      `json:"version" gorm:"not null;uniqueIndex:idx_post_revisions_version"` // 文章内从 1 递增
+	Title     string    `json:"title" gorm:"not null;size:50"`
+	Content   string    `json:"content" gorm:"not null;type:mediumtext"`
+	Note      string    `json:"note" gorm:"size:200"` // 修改说明
+	EditorID  uint      `json:"editor_id" gorm:"index;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r *PostRevision) BeforeUpdate(tx *gorm.DB) error {
+	return errors.New("post revisions are immutable")
+}
+
+// 修订列表中的一项，不含内容
+type PostRevisionSummary struct {
+	Version   uint      `json:"version"`
+	Title     string    `json:"title"`
+	Note      string    `json:"note"`
+	EditorID  uint      `json:"editor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PagePostRevisionResponse struct {
+	Total     int64                 `json:"total"`
+	Revisions []PostRevisionSummary `json:"revisions"`
+}
+
+// 两个修订之间的差异：标题整体比较，内容按行比较
+type PostRevisionDiffResponse struct {
+	PostID    uint             `json:"post_id"`
+	From      uint             `json:"from"`
+	To        uint             `json:"to"`
+	FromTitle string           `json:"from_title"`
+	ToTitle   string           `json:"to_title"`
+	Additions int              `json:"additions"`
+	Deletions int              `json:"deletions"`
+	Lines     []utils.DiffLine `json:"lines"`
+}
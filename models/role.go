@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// 内置角色
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// 内置权限
+const (
+	PermPostsModerate    = "posts:moderate"    // 删除任意文章、修改审计状态
+	PermCommentsModerate = "comments:moderate" // 删除任意评论
+	PermUsersRead        = "users:read"        // 查看用户列表
+	PermUsersManage      = "users:manage"      // 管理用户（分配角色等）
+	PermRolesManage      = "roles:manage"      // 管理角色权限
+	PermInvitesManage    = "invites:manage"    // 不受配额限制地创建邀请码，管理全部邀请码
+	PermCategoriesManage = "categories:manage" // 管理文章分类
+)
+
+type Role struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"uniqueIndex;not null;size:20"`
+	Description string       `json:"description" gorm:"size:100"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type Permission struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Code        string `json:"code" gorm:"uniqueIndex;not null;size:50"`
+	Description string `json:"description" gorm:"size:100"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type UpdateRolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+type UpdateAuditStatusRequest struct {
+	AuditStatus string `json:"audit_status" binding:"required,oneof=active inactive"`
+}
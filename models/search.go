@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// 搜索类型
+const (
+	SearchTypePost    = "post"
+	SearchTypeComment = "comment"
+)
+
+// 搜索结果：Title、Snippet 已做 HTML 转义，匹配处用 <mark> 标出
+type SearchHit struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`      // 文章或评论 id
+	PostID    uint      `json:"post_id"` // 所属文章 id
+	Title     string    `json:"title"`   // 文章标题
+	Snippet   string    `json:"snippet"` // 内容片段
+	Score     float64   `json:"score"`   // 相关度，越大越相关（不同实现的取值范围不同）
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SearchResponse struct {
+	Query string      `json:"query"`
+	Type  string      `json:"type"`
+	Total int64       `json:"total"`
+	Hits  []SearchHit `json:"hits"`
+}
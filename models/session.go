@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// 登录会话：每次登录创建一个，与刷新令牌族一一对应，撤销后该设备需要重新登录
+type Session struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"-" gorm:"index;not null"`
+	FamilyID   string     `json:"-" gorm:"uniqueIndex;not null;size:64"` // 刷新令牌族
+	Device     string     `json:"device" gorm:"size:100"`                // 由 User-Agent 识别的设备描述，如 "Chrome on Windows"
+	UserAgent  string     `json:"user_agent" gorm:"size:512"`
+	IP         string     `json:"ip" gorm:"size:64"`
+	ExpiresAt  time.Time  `json:"expires_at"` // 最新刷新令牌的过期时间
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type SessionResponse struct {
+	Session
+	Current bool `json:"current"` // 是否为发起请求的会话
+}
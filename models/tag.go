@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// 文章标签：与文章多对多（post_tags），名称统一为小写
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null;size:30"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// 一篇文章最多的标签数
+const MaxPostTags = 10
+
+// 标签及已发布文章数
+type TagCount struct {
+	Name      string `json:"name"`
+	PostCount int64  `json:"post_count"`
+}
+
+type PageTagResponse struct {
+	Total int64      `json:"total"`
+	Tags  []TagCount `json:"tags"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// 刷新令牌：服务端只保存摘要，每次刷新都会轮换
+// 同一次登录轮换出的令牌属于同一个族（FamilyID），重放已轮换的令牌时整族作废
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	FamilyID  string     `json:"family_id" gorm:"index;not null;size:64"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`    // 已轮换（被使用过）的时间
+	RevokedAt *time.Time `json:"revoked_at"` // 作废时间
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌有效期（秒）
+}
+
+// 已注销的访问令牌（按 jti 记录，令牌过期后即可清理）
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"` // 可选：同时作废该刷新令牌所在的令牌族
+}
+
+// 非对称签名密钥（RS256/EdDSA），多实例共享，按计划轮换
+type SigningKey struct {
+	ID          uint      `gorm:"primaryKey"`
+	Kid         string    `gorm:"uniqueIndex;not null;size:64"`
+	Algorithm   string    `gorm:"not null;size:10;index"`
+	PrivateKey  string    `gorm:"not null;type:text"` // PKCS#8 PEM，AES-GCM 加密
+	ActivatesAt time.Time `gorm:"index"`              // 生效时间：提前发布到 JWKS，生效后才用于签名
+	CreatedAt   time.Time
+}
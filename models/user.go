@@ -1,31 +1,59 @@
 package models
 
 import (
+	"fmt"
+	"path"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type User struct {
-	ID         uint           `json:"id" gorm:"primaryKey"`
-	Username   string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
-	Email      string         `json:"email" gorm:"uniqueIndex;not null;size:100"`
-	Password   string         `json:"-" gorm:"not null"`
-	PostNumber uint           `json:"post_number" gorm:"default:0"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
-	Posts      []Post
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Username        string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
+	Email           string         `json:"email" gorm:"uniqueIndex;not null;size:100"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at"` // 邮箱验证时间，未验证时不能发表文章和评论
+	Password        string         `json:"-" gorm:"not null"`
+	PostNumber      uint           `json:"post_number" gorm:"default:0"`
+	Role            string         `json:"role" gorm:"not null;size:20;default:user;index"` // 角色：user、moderator、admin
+	TokenVersion    uint           `json:"-" gorm:"default:0"`                              // 令牌版本：退出所有设备时 +1，与令牌中的版本不一致即失效
+	InvitedByID     *uint          `json:"invited_by" gorm:"index"`                         // 邀请人，通过邀请码注册时记录
+	DisplayName     string         `json:"display_name" gorm:"size:50"`
+	Bio             string         `json:"bio" gorm:"size:500"`
+	Website         string         `json:"website" gorm:"size:200"`
+	Location        string         `json:"location" gorm:"size:100"`
+	SocialLinks     SocialLinks    `json:"social_links" gorm:"serializer:json;type:text"`
+	Avatar          string         `json:"-" gorm:"size:100"` // 头像文件名，各尺寸见 AvatarVariant
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+	Posts           []Post
 }
 
 type CreateUserRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=20"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Username   string `json:"username" binding:"required,min=3,max=20"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required,min=6"`
+	InviteCode string `json:"invite_code"` // 邀请注册模式下必填
 }
 
+// 社交账号链接：名称（如 github、twitter）到主页地址
+type SocialLinks map[string]string
+
+// 资料字段为空表示不修改，传空字符串清空；social_links 整体替换，传 {} 清空
 type UpdateUserRequest struct {
-	Email string `json:"email" binding:"omitempty,email"`
+	Email       string      `json:"email" binding:"omitempty,email"`
+	DisplayName *string     `json:"display_name" binding:"omitempty,max=50"`
+	Bio         *string     `json:"bio" binding:"omitempty,max=500"`
+	Website     *string     `json:"website" binding:"omitempty,max=200"`
+	Location    *string     `json:"location" binding:"omitempty,max=100"`
+	SocialLinks SocialLinks `json:"social_links" binding:"omitempty,max=10"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
 }
 
 type LoginRequest struct {
@@ -34,11 +62,54 @@ type LoginRequest struct {
 }
 
 type UserResponse struct {
-	ID         uint      `json:"id"`
+	ID            uint      `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	CreatedAt     time.Time `json:"created_at"`
+	PostNumber    uint      `json:"post_number"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	InvitedBy     *uint     `json:"invited_by,omitempty"`
+	ProfileFields
+}
+
+// 公开资料字段
+type ProfileFields struct {
+	DisplayName string      `json:"display_name"`
+	Bio         string      `json:"bio"`
+	Website     string      `json:"website"`
+	Location    string      `json:"location"`
+	SocialLinks SocialLinks `json:"social_links"`
+	Avatar      *AvatarURLs `json:"avatar"` // 未上传头像时为 null
+}
+
+// 公开资料：不包含邮箱、角色等私有字段，附带已发布的文章
+type PublicProfileResponse struct {
 	Username   string    `json:"username"`
-	Email      string    `json:"email"`
-	CreatedAt  time.Time `json:"created_at"`
 	PostNumber uint      `json:"post_number"`
+	CreatedAt  time.Time `json:"created_at"`
+	ProfileFields
+	Posts []Post `json:"posts"`
+	Total int64  `json:"total"`
+}
+
+// 头像尺寸（正方形边长，像素）
+const (
+	AvatarSizeSmall  = 48
+	AvatarSizeMedium = 128
+	AvatarSizeLarge  = 256
+)
+
+type AvatarURLs struct {
+	Small  string `json:"small"`
+	Medium string `json:"medium"`
+	Large  string `json:"large"`
+}
+
+// 头像某一尺寸的文件名：avatars/1-xxxx.png -> avatars/1-xxxx-128.png
+func AvatarVariant(avatar string, size int) string {
+	ext := path.Ext(avatar)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(avatar, ext), size, ext)
 }
 
 type StatisticUserResponse struct {
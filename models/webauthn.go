@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+)
+
+// WebAuthn 挑战用途
+const (
+	WebAuthnChallengeRegistration = "registration"
+	WebAuthnChallengeLogin        = "login"
+)
+
+// 通行密钥（WebAuthn 凭据）：只保存公钥
+type WebAuthnCredential struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"-" gorm:"index;not null"`
+	Name         string     `json:"name" gorm:"size:100"`
+	CredentialID string     `json:"credential_id" gorm:"uniqueIndex;not null;size:255"` // base64url
+	PublicKey    []byte     `json:"-" gorm:"not null"`                                  // COSE 编码的公钥
+	SignCount    uint32     `json:"-"`                                                  // 签名计数器，用于发现被克隆的认证器
+	AAGUID       string     `json:"aaguid" gorm:"size:36"`                              // 认证器型号
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// 注册、登录仪式的挑战：一次性使用，过期失效
+type WebAuthnChallenge struct {
+	ID        string    `gorm:"primaryKey;size:64"` // 返回给客户端的 session_id
+	UserID    uint      `gorm:"index"`              // 注册时为当前用户；登录时指定了用户名则为该用户，否则为 0
+	Purpose   string    `gorm:"size:20;not null"`
+	Challenge string    `gorm:"size:64;not null"` // base64url
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+type BeginPasskeyLoginRequest struct {
+	Username string `json:"username"` // 可选：为空时由认证器选择可发现凭据
+}
+
+// 客户端 PublicKeyCredential.toJSON() 的结果，二进制字段均为 base64url
+type PasskeyCredential struct {
+	ID       string                    `json:"id" binding:"required"`
+	Type     string                    `json:"type" binding:"required"`
+	Response PasskeyCredentialResponse `json:"response" binding:"required"`
+}
+
+type PasskeyCredentialResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AttestationObject string `json:"attestationObject"` // 注册
+	AuthenticatorData string `json:"authenticatorData"` // 登录
+	Signature         string `json:"signature"`         // 登录
+	UserHandle        string `json:"userHandle"`        // 登录
+}
+
+type FinishPasskeyRegistrationRequest struct {
+	SessionID  string            `json:"session_id" binding:"required"`
+	Name       string            `json:"name" binding:"max=100"`
+	Credential PasskeyCredential `json:"credential" binding:"required"`
+}
+
+type FinishPasskeyLoginRequest struct {
+	SessionID  string            `json:"session_id" binding:"required"`
+	Credential PasskeyCredential `json:"credential" binding:"required"`
+}
+
+// 注册、登录选项：public_key 可直接传给 PublicKeyCredential.parseCreationOptionsFromJSON / parseRequestOptionsFromJSON
+type PasskeyOptionsResponse struct {
+	SessionID string      `json:"session_id"`
+	PublicKey interface{} `json:"public_key"`
+}
+
+type PublicKeyCredentialCreationOptions struct {
+	RP                     RelyingParty                    `json:"rp"`
+	User                   PublicKeyCredentialUser         `json:"user"`
+	Challenge              string                          `json:"challenge"`
+	PubKeyCredParams       []PublicKeyCredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                           `json:"timeout"`
+	ExcludeCredentials     []PublicKeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection          `json:"authenticatorSelection"`
+	Attestation            string                          `json:"attestation"`
+}
+
+type PublicKeyCredentialRequestOptions struct {
+	Challenge        string                          `json:"challenge"`
+	Timeout          int64                           `json:"timeout"`
+	RPID             string                          `json:"rpId"`
+	AllowCredentials []PublicKeyCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                          `json:"userVerification"`
+}
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type PublicKeyCredentialUser struct {
+	ID          string `json:"id"` // 用户句柄，base64url
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type PublicKeyCredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type PublicKeyCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
@@ -1,7 +1,13 @@
 package router
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -9,21 +15,148 @@ import (
 	"gin-examples/project/config"
 	"gin-examples/project/handlers"
 	"gin-examples/project/middleware"
+	"gin-examples/project/models"
 	"gin-examples/project/services"
 	"gin-examples/project/utils"
 )
 
 func SetupRouter(cfg *config.Config, db *gorm.DB) *gin.Engine {
+	// 令牌参数
+	jwtOptions := &utils.JWTOptions{
+		Secret:   []byte(cfg.JWT.Secret),
+		Expire:   cfg.JWT.ExpireDuration(),
+		Issuer:   cfg.JWT.Issuer,
+		Audience: cfg.JWT.Audience,
+		Leeway:   cfg.JWT.LeewayDuration(),
+	}
+	// 非对称签名：密钥保存在数据库中，定时轮换
+	if cfg.JWT.Algorithm != "" && cfg.JWT.Algorithm != utils.AlgHS256 {
+		keyEncryptionKey, err := decodeEncryptionKey(cfg.JWT.KeyEncryptionKey)
+		if err != nil {
+			log.Fatalf("Invalid jwt.key_encryption_key: %v", err)
+		}
+		jwtOptions.Keys = utils.NewKeySet(cfg.JWT.Algorithm)
+		// 旧密钥保留到其签发的令牌全部过期
+		retain := jwtOptions.Expire + jwtOptions.Leeway + time.Minute
+		keyService := services.NewKeyService(db, jwtOptions.Keys, keyEncryptionKey, cfg.JWT.KeyRotationDuration(), 15*time.Minute, retain)
+		if err := keyService.Refresh(); err != nil {
+			log.Fatalf("Failed to load signing keys: %v", err)
+		}
+		keyService.StartRotation(5 * time.Minute)
+	}
+
 	// 初始化服务：用户
-	userService := services.NewUserService(db)
-	userHandler := handlers.NewUserHandler(userService, []byte(cfg.JWT.Secret))
+	inviteOnly := cfg.Auth.Registration.Mode == "invite"
+	services.SetPasswordHasher(newPasswordHasher(cfg.Auth.PasswordHash))
+	loginGuard := services.NewLoginGuard(services.NewLoginAttemptStore(cfg.Auth.Login.Store, db), services.LoginPolicy{
+		MaxAttempts:   cfg.Auth.Login.MaxAttempts,
+		IPMaxAttempts: cfg.Auth.Login.IPMaxAttempts,
+		Lockout:       cfg.Auth.Login.LockoutDuration(),
+		DelayBase:     cfg.Auth.Login.DelayBaseDuration(),
+		DelayMax:      cfg.Auth.Login.DelayMaxDuration(),
+		Window:        cfg.Auth.Login.WindowDuration(),
+	})
+	loginGuard.StartPruner(10 * time.Minute)
+	userService := services.NewUserService(db, loginGuard, services.PasswordPolicy{
+		MinLength:        cfg.Auth.PasswordPolicy.MinLength,
+		MaxLength:        cfg.Auth.PasswordPolicy.MaxLength,
+		RequireLetter:    cfg.Auth.PasswordPolicy.RequireLetter,
+		RequireMixedCase: cfg.Auth.PasswordPolicy.RequireMixedCase,
+		RequireDigit:     cfg.Auth.PasswordPolicy.RequireDigit,
+		RequireSymbol:    cfg.Auth.PasswordPolicy.RequireSymbol,
+		DisallowUsername: cfg.Auth.PasswordPolicy.DisallowUsername,
+	}, inviteOnly)
+	revocations := services.NewRevocationStore(cfg.JWT.Revocation, db)
+	services.StartRevocationPruner(revocations, 10*time.Minute)
+	tokenService := services.NewTokenService(db, jwtOptions, cfg.JWT.RefreshExpireDuration(), revocations)
+	mailer := newMailer(cfg.Mail)
+	verificationService := services.NewVerificationService(db, mailer, []byte(cfg.JWT.Secret), cfg.Server.PublicURL, cfg.Auth.EmailVerifyExpireDuration())
+	verificationHandler := handlers.NewVerificationHandler(verificationService)
+	twoFactorService := services.NewTwoFactorService(db, tokenService, loginGuard, twoFactorEncryptionKey(cfg, db), cfg.Auth.TwoFactor.Issuer, cfg.Auth.TwoFactor.ChallengeExpireDuration())
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	userHandler := handlers.NewUserHandler(userService, tokenService, verificationService, twoFactorService)
+
+	passwordService := services.NewPasswordService(db, userService, mailer, cfg.Server.PublicURL, cfg.Auth.PasswordResetExpireDuration())
+	passwordHandler := handlers.NewPasswordHandler(passwordService)
+
+	magicLinkService := services.NewMagicLinkService(db, userService, mailer, cfg.Server.PublicURL, cfg.Auth.MagicLinkExpireDuration())
+	magicLinkHandler := handlers.NewMagicLinkHandler(magicLinkService, tokenService, twoFactorService)
+
+	// 上传文件保存在本地目录，由下面的 /uploads 静态路由对外提供
+	services.SetFileStorage(&utils.LocalStorage{Dir: cfg.Upload.Dir, BaseURL: cfg.Server.PublicURL + "/uploads"})
+	avatarService := services.NewAvatarService(db, cfg.Upload.AvatarMaxSize)
+	avatarHandler := handlers.NewAvatarHandler(avatarService)
 
 	postService := services.NewPostService(db)
+	// 定时发布：启动时补发停机期间到期的文章，之后每 30 秒检查一次
+	postService.StartScheduler(30 * time.Second)
 	postHandler := handlers.NewPostHandler(postService)
+	postRevisionHandler := handlers.NewPostRevisionHandler(services.NewPostRevisionService(db))
+	tagHandler := handlers.NewTagHandler(services.NewTagService(db))
+	categoryHandler := handlers.NewCategoryHandler(services.NewCategoryService(db))
+
+	// 全文索引在启动时建立（可重复执行），失败时只记录日志，不影响启动
+	searcher := services.NewSearcher(db)
+	if err := searcher.Setup(); err != nil {
+		log.Println("Warning: failed to set up search index:", err)
+	}
+	searchHandler := handlers.NewSearchHandler(services.NewSearchService(searcher))
 
 	commentService := services.NewCommentService(db)
 	commentHandler := handlers.NewCommentHandler(commentService)
 
+	keyHandler := handlers.NewKeyHandler(jwtOptions.Keys)
+
+	accessTokenService := services.NewAccessTokenService(db)
+	accessTokenHandler := handlers.NewAccessTokenHandler(accessTokenService)
+
+	webAuthnOrigins := cfg.Auth.WebAuthn.Origins
+	if len(webAuthnOrigins) == 0 {
+		webAuthnOrigins = []string{cfg.Server.PublicURL}
+	}
+	webAuthnService := services.NewWebAuthnService(db, services.WebAuthnOptions{
+		RPID:            cfg.Auth.WebAuthn.RPID,
+		RPName:          cfg.Auth.WebAuthn.RPName,
+		Origins:         webAuthnOrigins,
+		ChallengeExpire: cfg.Auth.WebAuthn.ChallengeExpireDuration(),
+	})
+	passkeyHandler := handlers.NewPasskeyHandler(webAuthnService, tokenService)
+
+	inviteService := services.NewInviteService(db, cfg.Auth.Registration.InviteQuota, cfg.Auth.Registration.InviteExpireDuration())
+	inviteHandler := handlers.NewInviteHandler(inviteService)
+
+	deletionPolicy := cfg.Auth.AccountDeletion
+	if deletionPolicy != models.AccountDeletionAnonymize && deletionPolicy != models.AccountDeletionDelete {
+		log.Printf("Warning: unknown auth.account_deletion %q, using %s", deletionPolicy, models.AccountDeletionAnonymize)
+		deletionPolicy = models.AccountDeletionAnonymize
+	}
+	accountService := services.NewAccountService(db, twoFactorService, deletionPolicy)
+	accountHandler := handlers.NewAccountHandler(accountService, userService)
+
+	sessionService := services.NewSessionService(db)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+
+	roleService := services.NewRoleService(db)
+	adminHandler := handlers.NewAdminHandler(userService, roleService, postService, commentService)
+
+	// OIDC 单点登录
+	var oidcHandler *handlers.OIDCHandler
+	if cfg.OIDC.Enabled {
+		redirectURL := cfg.OIDC.RedirectURL
+		if redirectURL == "" {
+			redirectURL = cfg.Server.PublicURL + "/api/v1/users/oidc/callback"
+		}
+		oidcService := services.NewOIDCService(db, services.OIDCOptions{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+			AllowSignup:  cfg.OIDC.AllowSignup && !inviteOnly, // 邀请注册模式下 SSO 只能登录已有用户
+		})
+		oidcHandler = handlers.NewOIDCHandler(oidcService, tokenService, twoFactorService, cfg.Server.Cookie.Secure)
+	}
+
 	// ...
 
 	// var json = jsoniter.Config{
@@ -41,10 +174,17 @@ func SetupRouter(cfg *config.Config, db *gorm.DB) *gin.Engine {
 	// 创建 Gin 引擎
 	// Create a Gin router with default middleware (logger and recovery)
 	r := gin.Default()
+	// 登录防暴力破解按客户端 IP 计数，只有可信代理转发的 X-Forwarded-For 才可信（默认不信任任何代理）
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid server.trusted_proxies: %v", err)
+	}
 
 	// 全局中间件
 	r.Use(middleware.Logger())
-	r.Use(middleware.CORS())
+	r.Use(middleware.CORS(cfg.Server.CORS.AllowedOrigins))
+	if cfg.Server.AuthMode == "cookie" {
+		r.Use(middleware.CookieSession(sessionCookieOptions(cfg)))
+	}
 
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
@@ -53,37 +193,219 @@ func SetupRouter(cfg *config.Config, db *gorm.DB) *gin.Engine {
 		})
 	})
 
+	// 上传的文件（头像）
+	r.Static("/uploads", cfg.Upload.Dir)
+
+	// 令牌验签公钥（RS256/EdDSA），供其他服务校验本服务签发的令牌
+	r.GET("/.well-known/jwks.json", keyHandler.JWKS)
+
 	// 公开路由
 	public := r.Group("/api/v1")
 	{
 		public.POST("/users/register", userHandler.Register)
 		public.POST("/users/login", userHandler.Login)
+		public.POST("/users/login/2fa", userHandler.LoginTwoFactor)
+		public.POST("/users/login/magic", magicLinkHandler.SendMagicLink)
+		public.POST("/users/login/magic/verify", magicLinkHandler.Login)
+		public.POST("/users/login/passkey/begin", passkeyHandler.BeginLogin)
+		public.POST("/users/login/passkey/finish", passkeyHandler.FinishLogin)
+		public.POST("/users/token/refresh", userHandler.RefreshToken)
+		public.POST("/users/password/forgot", passwordHandler.ForgotPassword)
+		public.POST("/users/password/reset", passwordHandler.ResetPassword)
+		public.GET("/users/email/verify", verificationHandler.VerifyEmail)
+		if oidcHandler != nil {
+			public.GET("/users/oidc/login", oidcHandler.Login)
+			public.GET("/users/oidc/callback", oidcHandler.Callback)
+		}
 		public.GET("/users/sta", userHandler.StatisticPostAuditStatus)
+		public.GET("/users/:username", userHandler.GetPublicProfile)
 
-		public.GET("/posts", postHandler.ListPostAll)
-		public.GET("/posts/:id", postHandler.GetPostById)
+		public.GET("/posts", middleware.OptionalAuth(tokenService, models.ScopePostsRead), postHandler.ListPostAll)
+		public.GET("/posts/:id", middleware.OptionalAuth(tokenService, models.ScopePostsRead), postHandler.GetPostById)
 		public.POST("/posts/condition", postHandler.ListPostByCondition)
 		public.GET("/posts/comment/number/max", postHandler.GetPostByMaxCommentNumber)
+		public.GET("/tags", tagHandler.ListTags)
+		public.GET("/categories", categoryHandler.ListCategories)
+		public.GET("/search", searchHandler.Search)
 
-		public.GET("/comments/:postId", commentHandler.ListCommentByPostId)
+		public.GET("/comments/:postId", middleware.OptionalAuth(tokenService, models.ScopeCommentsRead), commentHandler.ListCommentByPostId)
 	}
 
-	// 需要认证的路由
+	// 需要认证的路由：只接受登录令牌
 	protected := r.Group("/api/v1")
-	log.Printf("Router auth jwt secret:%s\n", cfg.JWT.Secret)
-	protected.Use(middleware.Auth([]byte(cfg.JWT.Secret)))
+	log.Printf("Router auth jwt algorithm:%s\n", cfg.JWT.Algorithm)
+	protected.Use(middleware.Auth(tokenService))
 	{
-		protected.GET("/users/me", userHandler.GetProfile)
+		protected.POST("/users/logout", userHandler.Logout)
+		protected.POST("/users/logout/all", userHandler.LogoutAll)
+		protected.POST("/users/email/verify/resend", verificationHandler.ResendVerification)
 		protected.PUT("/users/me", userHandler.UpdateProfile)
+		protected.DELETE("/users/me", accountHandler.DeleteAccount)
+		protected.GET("/users/me/export", accountHandler.Export)
+		protected.POST("/users/me/avatar", avatarHandler.UploadAvatar)
+		protected.DELETE("/users/me/avatar", avatarHandler.DeleteAvatar)
+		protected.PUT("/users/me/password", userHandler.ChangePassword)
+		if cfg.Auth.TwoFactor.Enabled {
+			protected.GET("/users/me/2fa", twoFactorHandler.Status)
+			protected.POST("/users/me/2fa", twoFactorHandler.Setup)
+			protected.POST("/users/me/2fa/enable", twoFactorHandler.Enable)
+			protected.DELETE("/users/me/2fa", twoFactorHandler.Disable)
+			protected.POST("/users/me/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+		}
+		protected.GET("/users/me/tokens", accessTokenHandler.ListAccessTokens)
+		protected.POST("/users/me/tokens", accessTokenHandler.CreateAccessToken)
+		protected.DELETE("/users/me/tokens/:id", accessTokenHandler.RevokeAccessToken)
+		protected.GET("/users/me/passkeys", passkeyHandler.ListPasskeys)
+		protected.POST("/users/me/passkeys/register/begin", passkeyHandler.BeginRegistration)
+		protected.POST("/users/me/passkeys/register/finish", passkeyHandler.FinishRegistration)
+		protected.DELETE("/users/me/passkeys/:id", passkeyHandler.DeletePasskey)
+		protected.GET("/users/me/invites", inviteHandler.ListInvites)
+		protected.POST("/users/me/invites", inviteHandler.CreateInvite)
+		protected.DELETE("/users/me/invites/:id", inviteHandler.RevokeInvite)
+		protected.GET("/users/me/sessions", sessionHandler.ListSessions)
+		protected.DELETE("/users/me/sessions/:id", sessionHandler.RevokeSession)
+	}
 
-		protected.POST("/posts/me", postHandler.CreatePost)
-		protected.GET("/posts/me", postHandler.ListPost)
-		protected.PUT("/posts/me", postHandler.UpdatePost)
-		protected.DELETE("/posts/me/:id", postHandler.DeletePost)
+	// 需要认证的路由：同时接受拥有对应 scope 的个人访问令牌
+	scoped := r.Group("/api/v1")
+	{
+		scoped.GET("/users/me", middleware.AuthWithScope(tokenService, models.ScopeProfileRead), userHandler.GetProfile)
+
+		scoped.POST("/posts/me", middleware.AuthWithScope(tokenService, models.ScopePostsWrite), postHandler.CreatePost)
+		scoped.GET("/posts/me", middleware.AuthWithScope(tokenService, models.ScopePostsRead), postHandler.ListPost)
+		scoped.PUT("/posts/me", middleware.AuthWithScope(tokenService, models.ScopePostsWrite), postHandler.UpdatePost)
+		scoped.DELETE("/posts/me/:id", middleware.AuthWithScope(tokenService, models.ScopePostsWrite), postHandler.DeletePost)
+		scoped.GET("/posts/me/:id/revisions", middleware.AuthWithScope(tokenService, models.ScopePostsRead), postRevisionHandler.ListRevisions)
+		scoped.GET("/posts/me/:id/revisions/diff", middleware.AuthWithScope(tokenService, models.ScopePostsRead), postRevisionHandler.DiffRevisions)
+		scoped.GET("/posts/me/:id/revisions/:version", middleware.AuthWithScope(tokenService, models.ScopePostsRead), postRevisionHandler.GetRevision)
+		scoped.POST("/posts/me/:id/revisions/:version/restore", middleware.AuthWithScope(tokenService, models.ScopePostsWrite), postRevisionHandler.RestoreRevision)
 
-		protected.POST("/comments", commentHandler.CreateComment)
-		protected.DELETE("/comments/me/:postId/:id", commentHandler.DeleteComment)
+		scoped.POST("/comments", middleware.AuthWithScope(tokenService, models.ScopeCommentsWrite), commentHandler.CreateComment)
+		scoped.DELETE("/comments/me/:postId/:id", middleware.AuthWithScope(tokenService, models.ScopeCommentsWrite), commentHandler.DeleteComment)
+	}
+
+	// 管理后台：按权限分组
+	admin := r.Group("/api/v1/admin")
+	admin.Use(middleware.Auth(tokenService))
+	{
+		users := admin.Group("/users")
+		users.GET("", middleware.RequirePermission(models.PermUsersRead), adminHandler.ListUsers)
+		users.PUT("/:id/role", middleware.RequirePermission(models.PermRolesManage), adminHandler.UpdateUserRole)
+		users.POST("/:id/verification", middleware.RequirePermission(models.PermUsersManage), verificationHandler.AdminResendVerification)
+		users.POST("/:id/verify", middleware.RequirePermission(models.PermUsersManage), verificationHandler.AdminForceVerify)
+		users.DELETE("/:id/2fa", middleware.RequirePermission(models.PermUsersManage), twoFactorHandler.AdminReset)
+
+		roles := admin.Group("/roles", middleware.RequirePermission(models.PermRolesManage))
+		roles.GET("", adminHandler.ListRoles)
+		roles.PUT("/:name/permissions", adminHandler.UpdateRolePermissions)
+
+		posts := admin.Group("/posts", middleware.RequirePermission(models.PermPostsModerate))
+		posts.DELETE("/:id", adminHandler.DeletePost)
+		posts.PUT("/:id/audit", adminHandler.UpdatePostAuditStatus)
+		posts.GET("/:id/revisions", postRevisionHandler.AdminListRevisions)
+		posts.GET("/:id/revisions/diff", postRevisionHandler.AdminDiffRevisions)
+		posts.GET("/:id/revisions/:version", postRevisionHandler.AdminGetRevision)
+
+		comments := admin.Group("/comments", middleware.RequirePermission(models.PermCommentsModerate))
+		comments.DELETE("/:id", adminHandler.DeleteComment)
+
+		categories := admin.Group("/categories", middleware.RequirePermission(models.PermCategoriesManage))
+		categories.POST("", categoryHandler.CreateCategory)
+		categories.PUT("/:id", categoryHandler.UpdateCategory)
+		categories.DELETE("/:id", categoryHandler.DeleteCategory)
+
+		invites := admin.Group("/invites", middleware.RequirePermission(models.PermInvitesManage))
+		invites.GET("", inviteHandler.AdminListInvites)
+		invites.DELETE("/:id", inviteHandler.AdminRevokeInvite)
 	}
 
 	return r
 }
+
+// 解析 base64 编码的 32 字节加密密钥
+func decodeEncryptionKey(encoded string) ([]byte, error) {
+	if encoded == "" {
+		return nil, errors.New("not configured")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// TOTP 密钥加密密钥：开启两步验证，或已有用户开启过两步验证（登录时仍需校验）时必须配置，否则拒绝启动
+func twoFactorEncryptionKey(cfg *config.Config, db *gorm.DB) []byte {
+	key, err := decodeEncryptionKey(cfg.Auth.TwoFactor.EncryptionKey)
+	if err == nil {
+		return key
+	}
+	if !cfg.Auth.TwoFactor.Enabled {
+		enrolled, countErr := services.CountTwoFactorUsers(db)
+		if countErr != nil {
+			log.Fatalf("Failed to count two-factor users: %v", countErr)
+		}
+		if enrolled == 0 {
+			return nil
+		}
+	}
+	log.Fatalf("Invalid auth.two_factor.encryption_key: %v", err)
+	return nil
+}
+
+// Cookie 会话参数：刷新令牌 Cookie 只发送到 /api/v1/users 下的接口
+func sessionCookieOptions(cfg *config.Config) *middleware.SessionCookieOptions {
+	options := &middleware.SessionCookieOptions{
+		Domain:        cfg.Server.Cookie.Domain,
+		Secure:        cfg.Server.Cookie.Secure,
+		SameSite:      http.SameSiteLaxMode,
+		RefreshPath:   "/api/v1/users",
+		RefreshMaxAge: cfg.JWT.RefreshExpireDuration(),
+	}
+	switch strings.ToLower(cfg.Server.Cookie.SameSite) {
+	case "strict":
+		options.SameSite = http.SameSiteStrictMode
+	case "none":
+		options.SameSite = http.SameSiteNoneMode
+		if !options.Secure {
+			log.Println("Warning: server.cookie.same_site none requires secure, enabling secure")
+			options.Secure = true
+		}
+	}
+	return options
+}
+
+// 根据配置创建密码哈希算法
+func newPasswordHasher(cfg config.PasswordHashConfig) utils.PasswordHasher {
+	if cfg.Algorithm == "bcrypt" {
+		return &utils.BcryptHasher{Cost: cfg.BcryptCost}
+	}
+	hasher := utils.NewArgon2idHasher()
+	if cfg.Memory > 0 {
+		hasher.Memory = cfg.Memory
+	}
+	if cfg.Iterations > 0 {
+		hasher.Iterations = cfg.Iterations
+	}
+	if cfg.Parallelism > 0 {
+		hasher.Parallelism = cfg.Parallelism
+	}
+	return hasher
+}
+
+// 根据配置创建邮件发送器
+func newMailer(cfg config.MailConfig) utils.Mailer {
+	if cfg.Driver == "smtp" {
+		return &utils.SMTPMailer{
+			Host:     cfg.Host,
+			Port:     cfg.Port,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			From:     cfg.From,
+		}
+	}
+	return &utils.FileMailer{Dir: cfg.Dir, From: cfg.From}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 个人访问令牌前缀，据此与 JWT 区分
+const accessTokenPrefix = "pat_"
+
+// 最近使用时间的更新间隔，避免每个请求都写数据库
+const accessTokenTouchInterval = time.Minute
+
+// 个人访问令牌：创建、查询、撤销
+type AccessTokenService struct {
+	db *gorm.DB
+}
+
+func NewAccessTokenService(db *gorm.DB) *AccessTokenService {
+	return &AccessTokenService{db: db}
+}
+
+// 创建令牌，明文只在创建时返回
+func (s *AccessTokenService) CreateAccessToken(userID uint, req models.CreateAccessTokenRequest) (*models.CreateAccessTokenResponse, error) {
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.AccessTokenScopes, scope) {
+			return nil, utils.NewAppError(400, "Unknown scope: "+scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	random, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	token := accessTokenPrefix + random
+
+	record := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: utils.HashToken(token),
+		Prefix:    token[:len(accessTokenPrefix)+6],
+		Scopes:    scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		record.ExpiresAt = &expiresAt
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAccessTokenResponse{PersonalAccessToken: record, Token: token}, nil
+}
+
+// 查询用户的全部令牌（不含已撤销的）
+func (s *AccessTokenService) ListAccessTokens(userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("id DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// 撤销令牌
+func (s *AccessTokenService) RevokeAccessToken(userID, id uint) error {
+	result := s.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewAppError(404, "Access token not found")
+	}
+	return nil
+}
+
+// 撤销用户的全部个人访问令牌（需在事务中调用），修改、重置密码时使用
+func revokeAccessTokens(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// 是否为个人访问令牌
+func isPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, accessTokenPrefix)
+}
+
+// 校验个人访问令牌，转换为 Claims（不携带角色权限，只能访问声明了 scope 的接口）
+func validatePersonalAccessToken(db *gorm.DB, token string) (*utils.Claims, error) {
+	var record models.PersonalAccessToken
+	if err := db.Where("token_hash = ?", utils.HashToken(token)).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(401, "Invalid token")
+		}
+		return nil, err
+	}
+	if record.RevokedAt != nil {
+		return nil, utils.NewAppError(401, "Token revoked")
+	}
+	now := time.Now()
+	if record.ExpiresAt != nil && now.After(*record.ExpiresAt) {
+		return nil, utils.NewAppError(401, "Token expired")
+	}
+
+	var user models.User
+	if err := db.Select("id", "username").First(&user, record.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(401, "User not found")
+		}
+		return nil, err
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) > accessTokenTouchInterval {
+		if err := db.Model(&record).UpdateColumn("last_used_at", now).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &utils.Claims{
+		UserID:    user.ID,
+		Username:  user.Username,
+		TokenType: utils.TokenTypePersonalAccess,
+		Scopes:    record.Scopes,
+	}, nil
+}
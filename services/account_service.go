@@ -0,0 +1,350 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 导出文章、评论时每批查询的条数
+const exportBatchSize = 100
+
+// 注销账号时免密码的重新登录时限：没有密码的用户（SSO、免密登录、通行密钥）重新登录后在此时限内可以注销
+const accountReauthWindow = 10 * time.Minute
+
+// 账号：个人数据导出、注销
+type AccountService struct {
+	db               *gorm.DB
+	twoFactorService *TwoFactorService
+	deletionPolicy   string // anonymize、delete
+}
+
+func NewAccountService(db *gorm.DB, twoFactorService *TwoFactorService, deletionPolicy string) *AccountService {
+	return &AccountService{db: db, twoFactorService: twoFactorService, deletionPolicy: deletionPolicy}
+}
+
+// 导出个人数据：zip 中包含 profile.json、posts.json、comments.json、audit.json，边查询边写入 w
+func (s *AccountService) Export(user *models.User, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	now := time.Now()
+	create := func(name string) (io.Writer, error) {
+		return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+	}
+
+	f, err := create("profile.json")
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(f, models.AccountExportProfile{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Role:            user.Role,
+		PostNumber:      user.PostNumber,
+		InvitedBy:       user.InvitedByID,
+		ProfileFields:   NewProfileFields(user),
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		ExportedAt:      now,
+	}); err != nil {
+		return err
+	}
+
+	if f, err = create("posts.json"); err != nil {
+		return err
+	}
+	if err := writeJSONArray(f, s.db.Where("user_id = ?", user.ID), func(post *models.Post) interface{} {
+		return models.AccountExportPost{
+			ID:            post.ID,
+			Title:         post.Title,
+			Content:       post.Content,
+			CommentNumber: post.CommentNumber,
+			CommentStatus: post.CommentStatus,
+			Status:        post.Status,
+			PublishAt:     post.PublishAt,
+			PublishedAt:   post.PublishedAt,
+			AuditBy:       post.Audit.AuditBy,
+			AuditVersion:  post.Audit.AuditVersion,
+			AuditStatus:   post.Audit.AuditStatus,
+			CreatedAt:     post.CreatedAt,
+			UpdatedAt:     post.UpdatedAt,
+		}
+	}); err != nil {
+		return err
+	}
+
+	if f, err = create("comments.json"); err != nil {
+		return err
+	}
+	if err := writeJSONArray(f, s.db.Where("user_id = ?", user.ID), func(comment *models.Comment) interface{} {
+		return models.AccountExportComment{
+			ID:        comment.ID,
+			PostID:    comment.PostID,
+			Content:   comment.Content,
+			CreatedAt: comment.CreatedAt,
+			UpdatedAt: comment.UpdatedAt,
+		}
+	}); err != nil {
+		return err
+	}
+
+	audit, err := s.auditRecords(user.ID)
+	if err != nil {
+		return err
+	}
+	if f, err = create("audit.json"); err != nil {
+		return err
+	}
+	if err := writeJSON(f, audit); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (s *AccountService) auditRecords(userID uint) (*models.AccountExportAudit, error) {
+	var audit models.AccountExportAudit
+	tf, err := s.twoFactorService.find(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil {
+		audit.TwoFactorEnabledAt = tf.EnabledAt
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&audit.Sessions).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&audit.Identities).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&audit.AccessTokens).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&audit.Passkeys).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("created_by_id = ?", userID).Order("id").Find(&audit.Invites).Error; err != nil {
+		return nil, err
+	}
+	var redemption models.InviteRedemption
+	if err := s.db.Where("user_id = ?", userID).First(&redemption).Error; err == nil {
+		audit.InviteRedemption = &redemption
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &audit, nil
+}
+
+// 注销账号：校验密码，或当前会话是刚刚登录的（开启了两步验证时还需验证码），
+// 按配置匿名化或删除用户的内容，并删除全部登录凭据。sessionID 为当前访问令牌所属的会话
+func (s *AccountService) DeleteAccount(userID, sessionID uint, req models.DeleteAccountRequest) error {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(404, "User not found")
+		}
+		return err
+	}
+	if req.Password != "" {
+		if !checkPassword(user.Password, req.Password) {
+			return utils.NewAppError(401, "Invalid password")
+		}
+	} else if err := s.requireRecentLogin(userID, sessionID); err != nil {
+		return err
+	}
+
+	tf, err := s.twoFactorService.find(userID)
+	if err != nil {
+		return err
+	}
+	if tf != nil && tf.EnabledAt != nil {
+		if req.Code == "" {
+			return utils.NewAppError(400, "Two-factor code required")
+		}
+		if err := s.twoFactorService.verifyCode(tf, req.Code); err != nil {
+			return err
+		}
+	}
+
+	// 至少保留一个管理员，否则无法再分配角色
+	if user.Role == models.RoleAdmin {
+		var admins int64
+		if err := s.db.Model(&models.User{}).Where("role = ? AND id <> ?", models.RoleAdmin, userID).Count(&admins).Error; err != nil {
+			return err
+		}
+		if admins == 0 {
+			return utils.NewAppError(409, "Cannot delete the last admin")
+		}
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := removeCredentials(tx, userID); err != nil {
+			return err
+		}
+		if s.deletionPolicy == models.AccountDeletionDelete {
+			return deleteUserContent(tx, userID)
+		}
+		return anonymizeUser(tx, userID)
+	}); err != nil {
+		return err
+	}
+	removeAvatarFiles(user.Avatar)
+	return nil
+}
+
+// 当前会话在 accountReauthWindow 内登录（刷新令牌不会延长会话的登录时间）
+func (s *AccountService) requireRecentLogin(userID, sessionID uint) error {
+	reauthRequired := utils.NewAppErrorWithDetails(401, "Password or recent login required",
+		map[string]int{"reauth_window": int(accountReauthWindow.Seconds())})
+	if sessionID == 0 {
+		return reauthRequired
+	}
+	var session models.Session
+	if err := s.db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return reauthRequired
+		}
+		return err
+	}
+	if time.Since(session.CreatedAt) > accountReauthWindow {
+		return reauthRequired
+	}
+	return nil
+}
+
+// 删除用户的登录凭据和会话，撤销尚未使用的邀请码（需在事务中调用）
+func removeCredentials(tx *gorm.DB, userID uint) error {
+	for _, model := range []interface{}{
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.PersonalAccessToken{},
+		&models.TwoFactor{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.WebAuthnCredential{},
+		&models.WebAuthnChallenge{},
+		&models.PasswordResetToken{},
+		&models.MagicLinkToken{},
+	} {
+		if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.Invite{}).
+		Where("created_by_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// 已删除用户的用户名、邮箱占位值：长度超过注册时用户名的上限（20），且不是合法邮箱，不会与任何注册的用户冲突
+func deletedUserPlaceholder(userID uint) string {
+	return fmt.Sprintf("deleted-user-%08d", userID)
+}
+
+// 匿名化：文章和评论保留，用户名、邮箱替换为占位值，清空资料，用户标记为已删除（需在事务中调用）
+func anonymizeUser(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumns(map[string]interface{}{
+		"username":          deletedUserPlaceholder(userID),
+		"email":             deletedUserPlaceholder(userID),
+		"email_verified_at": nil,
+		"password":          "",
+		"role":              models.RoleUser,
+		"invited_by_id":     nil,
+		"display_name":      "",
+		"bio":               "",
+		"website":           "",
+		"location":          "",
+		"social_links":      nil,
+		"avatar":            "",
+		"token_version":     gorm.Expr("token_version + 1"),
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.User{}, userID).Error
+}
+
+// 物理删除用户、用户的文章（连同文章下的评论、修订和标签关联）和评论，并重新统计受影响文章的评论数（需在事务中调用）
+func deleteUserContent(tx *gorm.DB, userID uint) error {
+	// 批量删除评论时跳过评论删除钩子，评论数统一重新统计
+	skipHooks := tx.Session(&gorm.Session{SkipHooks: true})
+
+	var postIDs []uint
+	if err := tx.Model(&models.Comment{}).Where("user_id = ?", userID).Distinct().Pluck("post_id", &postIDs).Error; err != nil {
+		return err
+	}
+	if err := skipHooks.Unscoped().Where("user_id = ?", userID).Delete(&models.Comment{}).Error; err != nil {
+		return err
+	}
+	posts := tx.Unscoped().Model(&models.Post{}).Select("id").Where("user_id = ?", userID)
+	if err := skipHooks.Unscoped().Where("post_id IN (?)", posts).Delete(&models.Comment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("post_id IN (?)", posts).Delete(&models.PostRevision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM post_tags WHERE post_id IN (?)", posts).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Post{}).Error; err != nil {
+		return err
+	}
+	if err := recountComments(tx, postIDs); err != nil {
+		return err
+	}
+
+	// 邀请关系：被邀请用户不再记录邀请人
+	if err := tx.Model(&models.User{}).Where("invited_by_id = ?", userID).UpdateColumn("invited_by_id", nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.InviteRedemption{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("created_by_id = ?", userID).Delete(&models.Invite{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&models.User{}, userID).Error
+}
+
+func writeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// 分批查询并写入 JSON 数组，避免一次加载用户的全部记录
+func writeJSONArray[T any](w io.Writer, query *gorm.DB, item func(*T) interface{}) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	var batch []T
+	if err := query.FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			data, err := json.Marshal(item(&batch[i]))
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error; err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
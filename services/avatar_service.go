@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 头像原图的最大宽高（像素），超过时不解码
+const avatarMaxDimension = 4096
+
+// 上传文件存储，由 SetFileStorage 按配置替换
+var fileStorage utils.Storage = &utils.LocalStorage{Dir: "uploads", BaseURL: "/uploads"}
+
+// 设置上传文件存储（启动时调用）
+func SetFileStorage(storage utils.Storage) {
+	fileStorage = storage
+}
+
+// 头像各尺寸的访问地址，未上传头像时返回 nil
+func AvatarURLs(avatar string) *models.AvatarURLs {
+	if avatar == "" {
+		return nil
+	}
+	return &models.AvatarURLs{
+		Small:  fileStorage.URL(models.AvatarVariant(avatar, models.AvatarSizeSmall)),
+		Medium: fileStorage.URL(models.AvatarVariant(avatar, models.AvatarSizeMedium)),
+		Large:  fileStorage.URL(models.AvatarVariant(avatar, models.AvatarSizeLarge)),
+	}
+}
+
+// 删除头像的全部尺寸，失败只记录日志（文件已与用户解除关联）
+func removeAvatarFiles(avatar string) {
+	if avatar == "" {
+		return
+	}
+	for _, size := range []int{models.AvatarSizeSmall, models.AvatarSizeMedium, models.AvatarSizeLarge} {
+		if err := fileStorage.Delete(models.AvatarVariant(avatar, size)); err != nil {
+			log.Printf("delete avatar file %s failed: %v", avatar, err)
+		}
+	}
+}
+
+// 头像：校验类型和大小，裁剪为正方形并生成多个尺寸
+type AvatarService struct {
+	db      *gorm.DB
+	maxSize int64 // 上传文件大小上限（字节）
+}
+
+func NewAvatarService(db *gorm.DB, maxSize int64) *AvatarService {
+	return &AvatarService{db: db, maxSize: maxSize}
+}
+
+// 上传文件大小上限（字节）
+func (s *AvatarService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// 上传头像，替换旧头像
+func (s *AvatarService) UploadAvatar(userID uint, data []byte) (*models.User, error) {
+	if int64(len(data)) > s.maxSize {
+		return nil, utils.NewAppErrorWithDetails(413, "Avatar too large", map[string]int64{"max_size": s.maxSize})
+	}
+	// 先按文件内容判断类型，不信任文件名和 Content-Type
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, utils.NewAppError(415, "Avatar must be a JPEG, PNG or GIF image")
+	}
+	img, format, err := utils.DecodeImage(data, avatarMaxDimension)
+	if err != nil {
+		if errors.Is(err, utils.ErrImageTooLarge) {
+			return nil, utils.NewAppErrorWithDetails(400, "Avatar dimensions too large", map[string]int{"max_dimension": avatarMaxDimension})
+		}
+		return nil, utils.NewAppError(415, "Avatar must be a JPEG, PNG or GIF image")
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(404, "User not found")
+		}
+		return nil, err
+	}
+
+	// 每次上传使用新的文件名，避免浏览器、CDN 缓存旧头像
+	suffix, err := utils.GenerateRandomToken(6)
+	if err != nil {
+		return nil, err
+	}
+	square := utils.CropSquare(img)
+	var avatar string
+	for _, size := range []int{models.AvatarSizeLarge, models.AvatarSizeMedium, models.AvatarSizeSmall} {
+		encoded, ext, err := utils.EncodeImage(utils.ResizeSquare(square, size), format)
+		if err != nil {
+			return nil, err
+		}
+		if avatar == "" {
+			avatar = fmt.Sprintf("avatars/%d-%s%s", userID, suffix, ext)
+		}
+		if err := fileStorage.Put(models.AvatarVariant(avatar, size), encoded); err != nil {
+			removeAvatarFiles(avatar)
+			return nil, err
+		}
+	}
+
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("avatar", avatar).Error; err != nil {
+		removeAvatarFiles(avatar)
+		return nil, err
+	}
+	removeAvatarFiles(user.Avatar)
+	user.Avatar = avatar
+	return &user, nil
+}
+
+// 删除头像
+func (s *AvatarService) DeleteAvatar(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(404, "User not found")
+		}
+		return nil, err
+	}
+	if user.Avatar == "" {
+		return &user, nil
+	}
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("avatar", "").Error; err != nil {
+		return nil, err
+	}
+	removeAvatarFiles(user.Avatar)
+	user.Avatar = ""
+	return &user, nil
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 文章分类：树形分类的维护和查询
+type CategoryService struct {
+	db *gorm.DB
+}
+
+func NewCategoryService(db *gorm.DB) *CategoryService {
+	return &CategoryService{db: db}
+}
+
+// 查询分类树，附带每个分类（含子分类）下已发布的文章数
+func (s *CategoryService) ListCategoryTree() ([]*models.CategoryNode, error) {
+	var categories []models.Category
+	if err := s.db.Order("name").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		CategoryID uint
+		Total      int64
+	}
+	if err := s.db.Model(&models.Post{}).
+		Select("category_id, COUNT(*) AS total").
+		Where("category_id IS NOT NULL AND status = ? AND audit_status = ?", models.PostStatusPublished, "active").
+		Group("category_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*models.CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &models.CategoryNode{
+			ID:       category.ID,
+			Name:     category.Name,
+			Slug:     category.Slug,
+			Children: []*models.CategoryNode{},
+		}
+	}
+	// 文章数累加到分类自身及全部上级分类
+	paths := make(map[uint]string, len(categories))
+	for _, category := range categories {
+		paths[category.ID] = category.Path
+	}
+	for _, count := range counts {
+		for _, id := range categoryPathIDs(paths[count.CategoryID]) {
+			if node, ok := nodes[id]; ok {
+				node.PostCount += count.Total
+			}
+		}
+	}
+
+	roots := []*models.CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[*category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return roots, nil
+}
+
+// 创建分类
+func (s *CategoryService) CreateCategory(req models.CreateCategoryRequest) (*models.Category, error) {
+	if err := s.checkSlug(req.Slug, 0); err != nil {
+		return nil, err
+	}
+	parentPath := "/"
+	if req.ParentID != nil {
+		parent, err := s.findParent(*req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if categoryDepth(parent.Path)+1 > models.MaxCategoryDepth {
+			return nil, utils.NewAppErrorWithDetails(400, "Category too deep", map[string]int{"max_depth": models.MaxCategoryDepth})
+		}
+		parentPath = parent.Path
+	}
+
+	category := models.Category{Name: req.Name, Slug: req.Slug, ParentID: req.ParentID, Path: parentPath}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+		// 路径包含自身 id，创建后才能确定
+		category.Path = fmt.Sprintf("%s%d/", parentPath, category.ID)
+		return tx.Model(&category).UpdateColumn("path", category.Path).Error
+	}); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// 修改分类：修改上级分类时整棵子树随之移动
+func (s *CategoryService) UpdateCategory(id uint, req models.UpdateCategoryRequest) (*models.Category, error) {
+	category, err := s.findCategory(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSlug(req.Slug, id); err != nil {
+		return nil, err
+	}
+
+	parentPath := "/"
+	if req.ParentID != nil {
+		parent, err := s.findParent(*req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(parent.Path, category.Path) {
+			return nil, utils.NewAppError(409, "Category cannot be moved under itself")
+		}
+		parentPath = parent.Path
+	}
+	newPath := fmt.Sprintf("%s%d/", parentPath, category.ID)
+
+	if newPath != category.Path {
+		// 子树中最深的分类移动后不能超过最大层级
+		var descendants []string
+		if err := s.db.Model(&models.Category{}).Where("path LIKE ?", category.Path+"%").Pluck("path", &descendants).Error; err != nil {
+			return nil, err
+		}
+		deepest := 0
+		for _, path := range descendants {
+			deepest = max(deepest, categoryDepth(path)-categoryDepth(category.Path))
+		}
+		if categoryDepth(newPath)+deepest > models.MaxCategoryDepth {
+			return nil, utils.NewAppErrorWithDetails(400, "Category too deep", map[string]int{"max_depth": models.MaxCategoryDepth})
+		}
+	}
+
+	oldPath := category.Path
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(category).Updates(map[string]interface{}{
+			"name":      req.Name,
+			"slug":      req.Slug,
+			"parent_id": req.ParentID,
+		}).Error; err != nil {
+			return err
+		}
+		if newPath == oldPath {
+			return nil
+		}
+		// 子树的路径都以原路径开头，替换为新路径
+		return tx.Model(&models.Category{}).
+			Where("path LIKE ?", oldPath+"%").
+			UpdateColumn("path", gorm.Expr("REPLACE(path, ?, ?)", oldPath, newPath)).Error
+	}); err != nil {
+		return nil, err
+	}
+	category.ParentID = req.ParentID
+	category.Path = newPath
+	return category, nil
+}
+
+// 删除分类：有子分类时不能删除，分类下的文章变为未分类
+func (s *CategoryService) DeleteCategory(id uint) error {
+	category, err := s.findCategory(id)
+	if err != nil {
+		return err
+	}
+	var children int64
+	if err := s.db.Model(&models.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return utils.NewAppError(409, "Category has subcategories")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Post{}).Where("category_id = ?", id).UpdateColumn("category_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(category).Error
+	})
+}
+
+func (s *CategoryService) findCategory(id uint) (*models.Category, error) {
+	var category models.Category
+	if err := s.db.First(&category, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(404, "Category not found")
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (s *CategoryService) findParent(id uint) (*models.Category, error) {
+	var parent models.Category
+	if err := s.db.First(&parent, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(400, "Parent category not found")
+		}
+		return nil, err
+	}
+	return &parent, nil
+}
+
+func (s *CategoryService) checkSlug(slug string, excludeID uint) error {
+	var count int64
+	if err := s.db.Model(&models.Category{}).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return utils.NewAppError(409, "Category slug exist")
+	}
+	return nil
+}
+
+// 分类层级：/1/ 为 1，/1/4/ 为 2
+func categoryDepth(path string) int {
+	return strings.Count(path, "/") - 1
+}
+
+// 路径中的分类 id，从根到自身
+func categoryPathIDs(path string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if id, err := strconv.ParseUint(part, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// 检查文章的分类是否存在：id 为空或 0 时返回 nil（未分类）
+func resolvePostCategory(db *gorm.DB, id *uint) (*uint, error) {
+	if id == nil || *id == 0 {
+		return nil, nil
+	}
+	var count int64
+	if err := db.Model(&models.Category{}).Where("id = ?", *id).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, utils.NewAppError(400, "Category not found")
+	}
+	return id, nil
+}
+
+// 分类子树的文章筛选条件
+func categorySubtree(db *gorm.DB, id uint) (*gorm.DB, error) {
+	var category models.Category
+	if err := db.First(&category, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(404, "Category not found")
+		}
+		return nil, err
+	}
+	return db.Model(&models.Category{}).Select("id").Where("path LIKE ?", category.Path+"%"), nil
+}
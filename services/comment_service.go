@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -21,9 +22,14 @@ func NewCommentService(db *gorm.DB) *CommentService {
 
 // 创建评论
 func (s *CommentService) CreateComment(userId uint, req models.CreateCommentRequest) (*models.Comment, error) {
-	// 检查文章是否已存在
+	// 邮箱验证后才能发表评论
+	if err := requireVerifiedEmail(s.db, userId); err != nil {
+		return nil, err
+	}
+
+	// 检查文章是否已存在，未发布的文章不能评论
 	var existingPost models.Post
-	if err := s.db.First(&existingPost, req.PostID).Error; err != nil {
+	if err := s.db.Scopes(publishedPosts).First(&existingPost, req.PostID).Error; err != nil {
 		return nil, utils.NewAppError(409, "Post not exist")
 	}
 
@@ -43,7 +49,16 @@ func (s *CommentService) CreateComment(userId uint, req models.CreateCommentRequ
 }
 
 // 查询文章的全部评论（查询文章时，通过 preload 可以自动关联查询出评论。评论分页需要继续使用此函数。）
-func (s *CommentService) ListCommentByPostId(postId uint, pageNo, pageSize int) ([]models.Comment, error) {
+// 未发布文章的评论只有作者（viewerId）可以查看
+func (s *CommentService) ListCommentByPostId(postId, viewerId uint, pageNo, pageSize int) ([]models.Comment, error) {
+	var post models.Post
+	if err := s.db.Select("id").Scopes(visibleTo(viewerId)).First(&post, postId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(404, "Post not exist")
+		}
+		return nil, err
+	}
+
 	var comments []models.Comment
 	if err := s.db.Scopes(utils.Sql.Paginate(pageNo, pageSize)).
 		Where("post_id = ?", postId).
@@ -76,6 +91,15 @@ func (s *CommentService) ListCommentByPostId(postId uint, pageNo, pageSize int)
 // 	}
 // }
 
+// 版主删除任意评论
+func (s *CommentService) ModerateDeleteComment(id uint) (bool, error) {
+	var existingComment models.Comment
+	if err := s.db.First(&existingComment, id).Error; err != nil {
+		return false, utils.NewAppError(404, "Comment not exist")
+	}
+	return s.DeleteComment(existingComment.UserID, existingComment.PostID, id)
+}
+
 func (s *CommentService) DeleteComment(userId uint, postId uint, id uint) (bool, error) {
 	// 查询要删除的数据
 	fmt.Printf("删除 userId=%d, postId=%d, id=%d \n", userId, postId, id)
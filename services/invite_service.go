@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 邀请码：创建、查询、撤销；注册时由 UserService 核销
+type InviteService struct {
+	db     *gorm.DB
+	quota  int           // 普通用户可邀请的人数（所有邀请码 MaxUses 之和）
+	expire time.Duration // 邀请码默认有效期
+}
+
+func NewInviteService(db *gorm.DB, quota int, expire time.Duration) *InviteService {
+	return &InviteService{db: db, quota: quota, expire: expire}
+}
+
+// 创建邀请码。unlimited 为 true（拥有 invites:manage 权限）时不受配额限制，并可指定有效期
+func (s *InviteService) CreateInvite(userID uint, unlimited bool, req models.CreateInviteRequest) (*models.Invite, error) {
+	maxUses := req.MaxUses
+	if maxUses == 0 {
+		maxUses = 1
+	}
+	expiresAt := time.Now().Add(s.expire)
+	if unlimited && req.ExpiresInDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, req.ExpiresInDays)
+	}
+
+	code, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	invite := models.Invite{
+		Code:        code,
+		CreatedByID: userID,
+		MaxUses:     maxUses,
+		ExpiresAt:   expiresAt,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if !unlimited {
+			used, err := inviteQuotaUsed(tx, userID)
+			if err != nil {
+				return err
+			}
+			if used+maxUses > s.quota {
+				return utils.NewAppErrorWithDetails(403, "Invite quota exceeded", map[string]int{"quota": s.quota, "remaining": max(s.quota-used, 0)})
+			}
+		}
+		return tx.Create(&invite).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// 查询用户创建的邀请码及剩余配额
+func (s *InviteService) ListInvites(userID uint, unlimited bool) (*models.InviteListResponse, error) {
+	var invites []models.Invite
+	if err := s.db.Where("created_by_id = ?", userID).Order("id DESC").Find(&invites).Error; err != nil {
+		return nil, err
+	}
+
+	response := &models.InviteListResponse{Quota: -1, Remaining: -1, Invites: invites}
+	if !unlimited {
+		used, err := inviteQuotaUsed(s.db, userID)
+		if err != nil {
+			return nil, err
+		}
+		response.Quota = s.quota
+		response.Remaining = max(s.quota-used, 0)
+	}
+	return response, nil
+}
+
+// 查询全部邀请码（管理后台）
+func (s *InviteService) ListAllInvites(pageNo, pageSize int) ([]models.Invite, error) {
+	var invites []models.Invite
+	if err := s.db.Scopes(utils.Sql.Paginate(pageNo, pageSize)).
+		Order("id DESC").
+		Find(&invites).Error; err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// 撤销邀请码。all 为 true 时可撤销任何人的邀请码（管理后台），未使用的名额退回配额
+func (s *InviteService) RevokeInvite(userID, id uint, all bool) error {
+	query := s.db.Model(&models.Invite{}).Where("id = ? AND revoked_at IS NULL", id)
+	if !all {
+		query = query.Where("created_by_id = ?", userID)
+	}
+	result := query.Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewAppError(404, "Invite not found")
+	}
+	return nil
+}
+
+// 已占用的配额：有效邀请码按 MaxUses 计，已撤销、已过期的按实际使用次数计
+func inviteQuotaUsed(tx *gorm.DB, userID uint) (int, error) {
+	var used int
+	err := tx.Model(&models.Invite{}).
+		Select("COALESCE(SUM(CASE WHEN revoked_at IS NULL AND expires_at > ? THEN max_uses ELSE used_count END), 0)", time.Now()).
+		Where("created_by_id = ?", userID).
+		Scan(&used).Error
+	return used, err
+}
+
+// 核销邀请码（需在事务中调用），返回邀请码
+func redeemInvite(tx *gorm.DB, code string) (*models.Invite, error) {
+	var invite models.Invite
+	if err := tx.Where("code = ?", code).First(&invite).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(400, "Invalid invite code")
+		}
+		return nil, err
+	}
+
+	// 条件更新：并发注册时不会超过使用次数上限
+	result := tx.Model(&models.Invite{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ? AND used_count < max_uses", invite.ID, time.Now()).
+		UpdateColumn("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, utils.NewAppError(400, "Invite code expired or used up")
+	}
+	return &invite, nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"crypto"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 签名密钥管理：密钥保存在数据库中供多实例共享，按计划轮换
+//
+// 轮换时新密钥提前 publishAhead 生成并发布到 JWKS，到期后才开始用于签名，
+// 这样其他实例和下游服务在新密钥启用前已经拿到了它的公钥。
+// 旧密钥在被新密钥取代 retain 时间后删除（retain 不短于访问令牌的有效期），
+// 切换算法后旧算法的密钥同样保留到 retain 之后，期间仍可验签。
+// 私钥使用 encryptionKey（32 字节）加密后保存。
+type KeyService struct {
+	db            *gorm.DB
+	keys          *utils.KeySet
+	encryptionKey []byte
+	rotateEvery   time.Duration
+	publishAhead  time.Duration
+	retain        time.Duration
+}
+
+func NewKeyService(db *gorm.DB, keys *utils.KeySet, encryptionKey []byte, rotateEvery, publishAhead, retain time.Duration) *KeyService {
+	return &KeyService{
+		db:            db,
+		keys:          keys,
+		encryptionKey: encryptionKey,
+		rotateEvery:   rotateEvery,
+		publishAhead:  publishAhead,
+		retain:        retain,
+	}
+}
+
+// 按需轮换、清理过期密钥，并重新加载到内存
+func (s *KeyService) Refresh() error {
+	now := time.Now()
+	algorithm := s.keys.Algorithm()
+
+	var latest models.SigningKey
+	err := s.db.Where("algorithm = ?", algorithm).Order("activates_at desc").First(&latest).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// 首次启动：立即生效
+		if err := s.createKey(now); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !latest.ActivatesAt.After(now.Add(s.publishAhead - s.rotateEvery)):
+		// 最新密钥即将使用满 rotateEvery，提前发布下一个密钥
+		if err := s.createKey(latest.ActivatesAt.Add(s.rotateEvery)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.prune(now); err != nil {
+		return err
+	}
+	return s.load()
+}
+
+// 定时检查轮换，并加载其他实例生成的密钥
+func (s *KeyService) StartRotation(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.Refresh(); err != nil {
+				log.Printf("refresh signing keys failed: %v", err)
+			}
+		}
+	}()
+}
+
+func (s *KeyService) createKey(activatesAt time.Time) error {
+	key, err := utils.GenerateSigningKey(s.keys.Algorithm())
+	if err != nil {
+		return err
+	}
+	privatePEM, err := utils.EncodePrivateKeyPEM(key.Private)
+	if err != nil {
+		return err
+	}
+	encrypted, err := utils.EncryptString(s.encryptionKey, privatePEM)
+	if err != nil {
+		return err
+	}
+	log.Printf("Generated %s signing key kid=%s activates at %v", key.Algorithm, key.Kid, activatesAt)
+	return s.db.Create(&models.SigningKey{
+		Kid:         key.Kid,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  encrypted,
+		ActivatesAt: activatesAt,
+	}).Error
+}
+
+// 删除已被取代超过 retain 的密钥，不区分算法：
+// 切换算法后，新算法的第一个密钥生效满 retain 时，旧算法签发的令牌已全部过期
+func (s *KeyService) prune(now time.Time) error {
+	// 取代者：当前算法中生效时间早于 now-retain 的最新密钥，比它更早生效的密钥都可以删除
+	var replacement models.SigningKey
+	err := s.db.Where("algorithm = ? AND activates_at <= ?", s.keys.Algorithm(), now.Add(-s.retain)).
+		Order("activates_at desc").First(&replacement).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.db.Where("activates_at < ?", replacement.ActivatesAt).Delete(&models.SigningKey{}).Error
+}
+
+// 加载全部密钥：当前算法的密钥用于签名，其他算法的密钥只用于验签和 JWKS
+func (s *KeyService) load() error {
+	var records []models.SigningKey
+	if err := s.db.Find(&records).Error; err != nil {
+		return err
+	}
+
+	keys := make([]*utils.SigningKey, 0, len(records))
+	for _, record := range records {
+		private, err := s.decryptPrivateKey(record)
+		if err != nil {
+			log.Printf("skip invalid signing key kid=%s: %v", record.Kid, err)
+			continue
+		}
+		keys = append(keys, &utils.SigningKey{
+			Kid:         record.Kid,
+			Algorithm:   record.Algorithm,
+			Private:     private,
+			Public:      private.Public(),
+			ActivatesAt: record.ActivatesAt,
+		})
+	}
+	s.keys.Replace(keys)
+	return nil
+}
+
+// 解密私钥
+func (s *KeyService) decryptPrivateKey(record models.SigningKey) (crypto.Signer, error) {
+	privatePEM, err := utils.DecryptString(s.encryptionKey, record.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return utils.ParsePrivateKeyPEM(privatePEM)
+}
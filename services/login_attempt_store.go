@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gin-examples/project/models"
+)
+
+// 登录失败状态存储
+type LoginAttemptStore interface {
+	Get(key string) (*models.LoginAttempt, error) // 不存在时返回 nil
+	// 原子地累加一次失败（上次失败早于 since 时从 1 重新计数），返回累加后的记录
+	Increment(key string, now, since time.Time) (*models.LoginAttempt, error)
+	// 锁定到 until 并清零计数；失败次数已被其他请求清零时返回 false
+	Lock(key string, until time.Time, maxAttempts int) (bool, error)
+	Delete(key string) error
+	PruneBefore(t time.Time) error // 清理 t 之前不再活跃的记录
+}
+
+// 根据配置创建存储：memory 只适合单实例部署，database 支持 SQLite、MySQL
+func NewLoginAttemptStore(kind string, db *gorm.DB) LoginAttemptStore {
+	if kind == "memory" {
+		return NewMemoryLoginAttemptStore()
+	}
+	return NewGormLoginAttemptStore(db)
+}
+
+// 内存实现
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: make(map[string]models.LoginAttempt)}
+}
+
+func (s *MemoryLoginAttemptStore) Get(key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Increment(key string, now, since time.Time) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.LastFailureAt.Before(since) {
+		attempt = models.LoginAttempt{Key: key, LockedUntil: attempt.LockedUntil}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	attempt.UpdatedAt = now
+	s.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Lock(key string, until time.Time, maxAttempts int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.Failures < maxAttempts {
+		return false, nil
+	}
+	attempt.Failures = 0
+	attempt.LockedUntil = until
+	attempt.UpdatedAt = time.Now()
+	s.attempts[key] = attempt
+	return true, nil
+}
+
+func (s *MemoryLoginAttemptStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) PruneBefore(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, attempt := range s.attempts {
+		if attempt.UpdatedAt.Before(t) && attempt.LockedUntil.Before(t) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
+
+// GORM 实现
+type GormLoginAttemptStore struct {
+	db *gorm.DB
+}
+
+func NewGormLoginAttemptStore(db *gorm.DB) *GormLoginAttemptStore {
+	return &GormLoginAttemptStore{db: db}
+}
+
+func (s *GormLoginAttemptStore) Get(key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	if err := s.db.Where("`key` = ?", key).First(&attempt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (s *GormLoginAttemptStore) Increment(key string, now, since time.Time) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// INSERT ... ON CONFLICT（SQLite）/ ON DUPLICATE KEY UPDATE（MySQL），计数在 SQL 中累加，并发失败不会丢失。
+		// MySQL 按顺序执行赋值，failures 必须在 last_failure_at 之前更新
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: []clause.Assignment{
+				{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("CASE WHEN last_failure_at < ? THEN 1 ELSE failures + 1 END", since)},
+				{Column: clause.Column{Name: "last_failure_at"}, Value: now},
+				{Column: clause.Column{Name: "updated_at"}, Value: now},
+			},
+		}).Create(&models.LoginAttempt{Key: key, Failures: 1, LastFailureAt: now, UpdatedAt: now}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("`key` = ?", key).First(&attempt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (s *GormLoginAttemptStore) Lock(key string, until time.Time, maxAttempts int) (bool, error) {
+	result := s.db.Model(&models.LoginAttempt{}).Where("`key` = ? AND failures >= ?", key, maxAttempts).
+		Updates(map[string]interface{}{"failures": 0, "locked_until": until})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (s *GormLoginAttemptStore) Delete(key string) error {
+	return s.db.Where("`key` = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+func (s *GormLoginAttemptStore) PruneBefore(t time.Time) error {
+	return s.db.Where("updated_at < ? AND locked_until < ?", t, t).Delete(&models.LoginAttempt{}).Error
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 登录防暴力破解策略
+type LoginPolicy struct {
+	MaxAttempts   int           // 同一用户名连续失败次数达到后锁定
+	IPMaxAttempts int           // 同一 IP 连续失败次数达到后锁定
+	Lockout       time.Duration // 锁定时长
+	DelayBase     time.Duration // 失败后的递增延迟：第 n 次失败后需等待 DelayBase * 2^(n-1)
+	DelayMax      time.Duration // 递增延迟上限
+	Window        time.Duration // 超过该时长没有失败，计数清零
+}
+
+// 登录锁定提示，放在响应的 error 字段中
+type LoginLockNotice struct {
+	Message     string    `json:"message"`
+	RetryAfter  int64     `json:"retry_after"` // 秒
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// 按用户名、客户端 IP 统计登录失败，递增延迟并临时锁定
+type LoginGuard struct {
+	store  LoginAttemptStore
+	policy LoginPolicy
+}
+
+func NewLoginGuard(store LoginAttemptStore, policy LoginPolicy) *LoginGuard {
+	return &LoginGuard{store: store, policy: policy}
+}
+
+// 定时清理过期记录
+func (g *LoginGuard) StartPruner(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := g.store.PruneBefore(time.Now().Add(-g.policy.Window)); err != nil {
+				log.Printf("prune login attempts failed: %v", err)
+			}
+		}
+	}()
+}
+
+// 登录前检查：处于锁定或延迟期内时拒绝
+func (g *LoginGuard) Check(username, clientIP string) error {
+	now := time.Now()
+	for _, key := range g.keys(username, clientIP) {
+		attempt, err := g.store.Get(key)
+		if err != nil {
+			return err
+		}
+		if attempt == nil {
+			continue
+		}
+		if until := g.blockedUntil(attempt, now); until.After(now) {
+			return g.lockError(attempt, until, now)
+		}
+	}
+	return nil
+}
+
+// 登录失败：累加计数，达到阈值时锁定。返回锁定错误（如果本次失败触发了锁定）
+func (g *LoginGuard) Fail(username, clientIP string) error {
+	now := time.Now()
+	var lockErr error
+	for _, key := range g.keys(username, clientIP) {
+		attempt, err := g.store.Increment(key, now, now.Add(-g.policy.Window))
+		if err != nil {
+			return err
+		}
+		if attempt.Failures < g.maxAttempts(key) {
+			continue
+		}
+		// 并发的失败请求可能同时达到阈值，只由清零计数的那一个设置锁定时间
+		until := now.Add(g.policy.Lockout)
+		locked, err := g.store.Lock(key, until, g.maxAttempts(key))
+		if err != nil {
+			return err
+		}
+		if !locked {
+			continue
+		}
+		attempt.LockedUntil = until
+		lockErr = g.lockError(attempt, until, now)
+	}
+	return lockErr
+}
+
+// 登录成功：清除该用户名的失败记录（IP 记录保留到过期，避免攻击者用自己的账号重置计数）
+func (g *LoginGuard) Succeed(username string) error {
+	return g.store.Delete(userAttemptKey(username))
+}
+
+func (g *LoginGuard) keys(username, clientIP string) []string {
+	keys := []string{userAttemptKey(username)}
+	if clientIP != "" {
+		keys = append(keys, "ip:"+clientIP)
+	}
+	return keys
+}
+
+func (g *LoginGuard) maxAttempts(key string) int {
+	if strings.HasPrefix(key, "ip:") {
+		return g.policy.IPMaxAttempts
+	}
+	return g.policy.MaxAttempts
+}
+
+// 锁定截止时间与递增延迟截止时间取较晚者
+func (g *LoginGuard) blockedUntil(attempt *models.LoginAttempt, now time.Time) time.Time {
+	until := attempt.LockedUntil
+	if attempt.Failures > 0 && now.Sub(attempt.LastFailureAt) <= g.policy.Window {
+		delay := float64(g.policy.DelayBase) * math.Pow(2, float64(attempt.Failures-1))
+		if delay > float64(g.policy.DelayMax) {
+			delay = float64(g.policy.DelayMax)
+		}
+		if next := attempt.LastFailureAt.Add(time.Duration(delay)); next.After(until) {
+			until = next
+		}
+	}
+	return until
+}
+
+func (g *LoginGuard) lockError(attempt *models.LoginAttempt, until, now time.Time) error {
+	message := "Too many failed login attempts, please retry later"
+	if attempt.LockedUntil.Equal(until) {
+		message = "Account temporarily locked due to too many failed login attempts"
+	}
+	return utils.NewAppErrorWithDetails(http.StatusTooManyRequests, message, LoginLockNotice{
+		Message:     message,
+		RetryAfter:  int64(math.Ceil(until.Sub(now).Seconds())),
+		LockedUntil: until,
+	})
+}
+
+func userAttemptKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 同一用户两封登录邮件的最小间隔，防止被用来轰炸邮箱
+const magicLinkResendInterval = time.Minute
+
+// 免密登录：邮件发送一次性登录链接，凭链接中的令牌登录
+type MagicLinkService struct {
+	db          *gorm.DB
+	userService *UserService
+	mailer      utils.Mailer
+	publicURL   string
+	expire      time.Duration
+}
+
+func NewMagicLinkService(db *gorm.DB, userService *UserService, mailer utils.Mailer, publicURL string, expire time.Duration) *MagicLinkService {
+	return &MagicLinkService{
+		db:          db,
+		userService: userService,
+		mailer:      mailer,
+		publicURL:   publicURL,
+		expire:      expire,
+	}
+}
+
+// 发送登录邮件。邮箱不存在或发送过于频繁时同样返回成功，避免泄露哪些邮箱已注册
+func (s *MagicLinkService) SendMagicLink(email string) error {
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("magic link requested for unknown email %s", email)
+			return nil
+		}
+		return err
+	}
+
+	var recent int64
+	if err := s.db.Model(&models.MagicLinkToken{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-magicLinkResendInterval)).
+		Count(&recent).Error; err != nil {
+		return err
+	}
+	if recent > 0 {
+		log.Printf("magic link for user %d throttled", user.ID)
+		return nil
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 之前发送的登录链接全部作废，只有最新一封邮件有效
+		if err := tx.Model(&models.MagicLinkToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.MagicLinkToken{
+			UserID:    user.ID,
+			Email:     user.Email,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().Add(s.expire),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/login/magic?token=%s", s.publicURL, url.QueryEscape(token))
+	return s.mailer.Send(utils.MailMessage{
+		To:      user.Email,
+		Subject: "登录链接",
+		Body: fmt.Sprintf("%s，你好：\n\n请在 %d 分钟内打开以下链接登录，链接只能使用一次：\n%s\n\n登录令牌：%s\n\n如果不是你本人操作，请忽略此邮件。\n",
+			user.Username, int(s.expire.Minutes()), link, token),
+	})
+}
+
+// 凭登录令牌登录，令牌只能使用一次；能收到邮件说明收件邮箱属于该用户，顺带完成邮箱验证。
+// 发送后修改过邮箱时新邮箱未经验证，只登录不验证
+func (s *MagicLinkService) Redeem(token string) (*models.User, error) {
+	var magicLink models.MagicLinkToken
+	if err := s.db.Where("token_hash = ?", utils.HashToken(token)).First(&magicLink).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(401, "Invalid login link")
+		}
+		return nil, err
+	}
+	if magicLink.UsedAt != nil || time.Now().After(magicLink.ExpiresAt) {
+		return nil, utils.NewAppError(401, "Login link expired")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证令牌只能使用一次
+		now := time.Now()
+		result := tx.Model(&models.MagicLinkToken{}).
+			Where("id = ? AND used_at IS NULL", magicLink.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewAppError(401, "Login link expired")
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ? AND email = ? AND email_verified_at IS NULL", magicLink.UserID, magicLink.Email).
+			Update("email_verified_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userService.GetUserByID(magicLink.UserID)
+}
@@ -0,0 +1,440 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 登录发起到回调的最长时间
+const OIDCStateExpire = 10 * time.Minute
+
+// OIDC 登录参数（来自配置 oidc.*）
+type OIDCOptions struct {
+	Issuer       string // 提供方地址，从 {Issuer}/.well-known/openid-configuration 获取端点
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string   // 回调地址，需在提供方登记
+	Scopes       []string // 至少包含 openid
+	AllowSignup  bool     // 首次登录时自动创建本地用户
+}
+
+// 提供方元数据（OpenID Connect Discovery）
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// ID Token 中用到的声明
+type oidcIDTokenClaims struct {
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     oidcBool `json:"email_verified"`
+	PreferredUsername string   `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+// 部分提供方以字符串 "true" 返回 email_verified
+type oidcBool bool
+
+func (b *oidcBool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	*b = oidcBool(s == "true")
+	return nil
+}
+
+// OIDC 授权码 + PKCE 登录：查找或创建本地用户并关联外部身份
+type OIDCService struct {
+	db         *gorm.DB
+	options    OIDCOptions
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	metadata *oidcProviderMetadata
+	keys     map[string]interface{} // kid -> 公钥
+}
+
+func NewOIDCService(db *gorm.DB, options OIDCOptions) *OIDCService {
+	return &OIDCService{
+		db:         db,
+		options:    options,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// 发起登录：保存 state、nonce、code_verifier，返回提供方的授权地址和 state。
+// 调用方需把 state 写入发起登录的浏览器的 Cookie，回调时一并校验
+func (s *OIDCService) AuthorizationURL() (string, string, error) {
+	metadata, err := s.providerMetadata()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := utils.GenerateRandomToken(48)
+	if err != nil {
+		return "", "", err
+	}
+
+	// 顺带清理过期的 state
+	if err := s.db.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLoginState{}).Error; err != nil {
+		return "", "", err
+	}
+	if err := s.db.Create(&models.OIDCLoginState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(OIDCStateExpire),
+	}).Error; err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", s.options.ClientID)
+	query.Set("redirect_uri", s.options.RedirectURL)
+	query.Set("scope", strings.Join(s.options.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// 处理回调：校验 state，用授权码换取 ID Token 并验签，返回关联的本地用户。
+// browserState 为发起登录时写入浏览器 Cookie 的 state，必须与回调参数一致，
+// 否则可能是攻击者把自己的授权回调地址发给受害者，使受害者登录攻击者的账号（登录 CSRF）
+func (s *OIDCService) Callback(code, state, browserState string) (*models.User, error) {
+	if code == "" || state == "" {
+		return nil, utils.NewAppError(400, "Missing code or state")
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, utils.NewAppError(400, "Login state mismatch")
+	}
+
+	// state 一次性使用
+	var loginState models.OIDCLoginState
+	if err := s.db.Where("state = ?", state).First(&loginState).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(400, "Invalid login state")
+		}
+		return nil, err
+	}
+	result := s.db.Where("state = ?", state).Delete(&models.OIDCLoginState{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || time.Now().After(loginState.ExpiresAt) {
+		return nil, utils.NewAppError(400, "Invalid login state")
+	}
+
+	rawIDToken, err := s.exchangeCode(code, loginState.CodeVerifier)
+	if err != nil {
+		log.Printf("oidc code exchange failed: %v", err)
+		return nil, utils.NewAppError(401, "OIDC code exchange failed")
+	}
+	claims, err := s.verifyIDToken(rawIDToken)
+	if err != nil {
+		log.Printf("oidc id token verification failed: %v", err)
+		return nil, utils.NewAppError(401, "Invalid ID token")
+	}
+	if claims.Nonce != loginState.Nonce {
+		return nil, utils.NewAppError(401, "Invalid ID token")
+	}
+
+	return s.findOrCreateUser(claims)
+}
+
+// 用授权码换取令牌，返回 id_token
+func (s *OIDCService) exchangeCode(code, verifier string) (string, error) {
+	metadata, err := s.providerMetadata()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.options.RedirectURL)
+	form.Set("code_verifier", verifier)
+	req, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// client_secret_basic
+	req.SetBasicAuth(url.QueryEscape(s.options.ClientID), url.QueryEscape(s.options.ClientSecret))
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := s.doJSON(req, &token); err != nil {
+		return "", err
+	}
+	if token.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return token.IDToken, nil
+}
+
+// 校验 ID Token：签名（按 kid 从 JWKS 选择公钥）、iss、aud、exp
+func (s *OIDCService) verifyIDToken(rawIDToken string) (*oidcIDTokenClaims, error) {
+	metadata, err := s.providerMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &oidcIDTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.publicKey(kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(s.options.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+	return claims, nil
+}
+
+// 查找关联的用户；未关联时按已验证的邮箱关联已有用户，或创建新用户
+func (s *OIDCService) findOrCreateUser(claims *oidcIDTokenClaims) (*models.User, error) {
+	issuer := claims.Issuer
+
+	var identity models.UserIdentity
+	err := s.db.Where("issuer = ? AND subject = ?", issuer, claims.Subject).First(&identity).Error
+	if err == nil {
+		var user models.User
+		if err := s.db.First(&user, identity.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, utils.NewAppError(401, "User not found")
+			}
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" {
+		return nil, utils.NewAppError(400, "OIDC provider did not return an email")
+	}
+
+	var user models.User
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("email = ?", claims.Email).First(&user).Error
+		switch {
+		case err == nil:
+			// 只有提供方确认过邮箱，才能关联到已有账号，避免通过伪造邮箱接管账号
+			if !claims.EmailVerified {
+				return utils.NewAppError(409, "Email already registered")
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if !s.options.AllowSignup {
+				return utils.NewAppError(403, "No account linked to this identity")
+			}
+			created, err := s.createUser(tx, claims)
+			if err != nil {
+				return err
+			}
+			user = *created
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:  user.ID,
+			Issuer:  issuer,
+			Subject: claims.Subject,
+			Email:   claims.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+var oidcUsernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// 创建本地用户：用户名取 preferred_username 或邮箱前缀，重名时追加随机后缀；密码随机，可通过找回密码设置
+func (s *OIDCService) createUser(tx *gorm.DB, claims *oidcIDTokenClaims) (*models.User, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = oidcUsernameInvalidChars.ReplaceAllString(base, "")
+	if len(base) > 14 {
+		base = base[:14]
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+
+	password, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		Email:    claims.Email,
+		Password: hashedPassword,
+	}
+	if claims.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	for i := 0; i < 5; i++ {
+		username := base
+		if i > 0 {
+			suffix, err := utils.GenerateRandomToken(3)
+			if err != nil {
+				return nil, err
+			}
+			username = base + "_" + oidcUsernameInvalidChars.ReplaceAllString(suffix, "")
+		}
+		if isReservedUsername(username) {
+			continue
+		}
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			user.Username = username
+			break
+		}
+	}
+	if user.Username == "" {
+		return nil, utils.NewAppError(409, "Username already exists")
+	}
+
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// 获取提供方元数据（首次使用时请求并缓存）
+func (s *OIDCService) providerMetadata() (*oidcProviderMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.metadata != nil {
+		return s.metadata, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(s.options.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var metadata oidcProviderMetadata
+	if err := s.doJSON(req, &metadata); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if metadata.Issuer != s.options.Issuer {
+		return nil, fmt.Errorf("oidc issuer mismatch: %q", metadata.Issuer)
+	}
+	s.metadata = &metadata
+	return s.metadata, nil
+}
+
+// 按 kid 查找提供方公钥，找不到时重新获取 JWKS（提供方可能已轮换密钥）
+func (s *OIDCService) publicKey(kid string) (interface{}, error) {
+	metadata, err := s.providerMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, metadata.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var jwks utils.JWKS
+	if err := s.doJSON(req, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		key, err := utils.JWKToPublicKey(jwk)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	s.keys = keys
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	return key, nil
+}
+
+func (s *OIDCService) doJSON(req *http.Request, v interface{}) error {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL, resp.Status, body)
+	}
+	return json.Unmarshal(body, v)
+}
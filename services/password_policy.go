@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gin-examples/project/utils"
+)
+
+// 密码策略：注册、重置密码、修改密码时校验，零值表示不限制
+type PasswordPolicy struct {
+	MinLength        int
+	MaxLength        int  // bcrypt 只使用前 72 字节
+	RequireLetter    bool // 至少一个字母
+	RequireMixedCase bool // 同时包含大写和小写字母
+	RequireDigit     bool
+	RequireSymbol    bool
+	DisallowUsername bool // 不能包含用户名
+}
+
+// 校验密码，不满足时返回 400，error 字段列出全部不满足的规则
+func (p PasswordPolicy) Validate(password, username string) error {
+	var hasLetter, hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasLetter, hasUpper = true, true
+		case unicode.IsLower(r):
+			hasLetter, hasLower = true, true
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	var violations []string
+	if p.MinLength > 0 && len([]rune(password)) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes", p.MaxLength))
+	}
+	if p.RequireLetter && !hasLetter {
+		violations = append(violations, "must contain a letter")
+	}
+	if p.RequireMixedCase && !(hasUpper && hasLower) {
+		violations = append(violations, "must contain both upper and lower case letters")
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+	if p.DisallowUsername && username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, "must not contain the username")
+	}
+
+	if len(violations) > 0 {
+		return utils.NewAppErrorWithDetails(400, "Password does not meet the policy", violations)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 找回密码：邮件发送一次性重置令牌，凭令牌重置密码
+type PasswordService struct {
+	db          *gorm.DB
+	userService *UserService
+	mailer      utils.Mailer
+	publicURL   string
+	resetExpire time.Duration
+}
+
+func NewPasswordService(db *gorm.DB, userService *UserService, mailer utils.Mailer, publicURL string, resetExpire time.Duration) *PasswordService {
+	return &PasswordService{
+		db:          db,
+		userService: userService,
+		mailer:      mailer,
+		publicURL:   publicURL,
+		resetExpire: resetExpire,
+	}
+}
+
+// 发送重置邮件。邮箱不存在时同样返回成功，避免泄露哪些邮箱已注册
+func (s *PasswordService) ForgotPassword(email string) error {
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("password reset requested for unknown email %s", email)
+			return nil
+		}
+		return err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 之前发送的重置令牌全部作废，只有最新一封邮件有效
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().Add(s.resetExpire),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.publicURL, url.QueryEscape(token))
+	return s.mailer.Send(utils.MailMessage{
+		To:      user.Email,
+		Subject: "重置密码",
+		Body: fmt.Sprintf("%s，你好：\n\n请在 %d 分钟内打开以下链接重置密码：\n%s\n\n重置令牌：%s\n\n如果不是你本人操作，请忽略此邮件。\n",
+			user.Username, int(s.resetExpire.Minutes()), link, token),
+	})
+}
+
+// 凭重置令牌设置新密码，令牌只能使用一次，已登录的会话全部失效
+func (s *PasswordService) ResetPassword(token, newPassword string) error {
+	var resetToken models.PasswordResetToken
+	if err := s.db.Where("token_hash = ?", utils.HashToken(token)).First(&resetToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(400, "Invalid reset token")
+		}
+		return err
+	}
+	if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		return utils.NewAppError(400, "Reset token expired")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证令牌只能使用一次
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", resetToken.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewAppError(400, "Reset token expired")
+		}
+		return s.userService.resetPassword(tx, resetToken.UserID, newPassword)
+	})
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 查询语言（语法见 utils.ParseQuery）支持的字段：
+//
+//	关键词        标题或内容包含
+//	title:x      标题包含
+//	content:x    内容包含
+//	author:x     作者用户名
+//	tag:x        带有标签
+//	category:x   分类（slug）及其子分类
+//	status:x     发布状态（未发布的文章只有作者可见）
+//	comments:n   评论数，可比较：>=3、<10、1..5
+//	created:d    创建日期（2026、2026-01、2026-01-15），可比较：>=2026-01、2026-01..2026-02
+type postQueryCompiler struct {
+	db *gorm.DB
+}
+
+type postScope = func(*gorm.DB) *gorm.DB
+
+// 解析查询语句并编译为 GORM scope，语句有误时返回 422，附带出错位置
+func compilePostQuery(db *gorm.DB, q string) (postScope, error) {
+	node, err := utils.ParseQuery(q)
+	if err != nil {
+		return nil, postQueryError(err)
+	}
+	scope, err := (&postQueryCompiler{db: db}).compile(node)
+	if err != nil {
+		return nil, postQueryError(err)
+	}
+	return scope, nil
+}
+
+func postQueryError(err error) error {
+	var queryErr *utils.QueryError
+	if errors.As(err, &queryErr) {
+		return utils.NewAppErrorWithDetails(422, "Invalid query", queryErr)
+	}
+	return err
+}
+
+func (c *postQueryCompiler) compile(node utils.QueryNode) (postScope, error) {
+	switch n := node.(type) {
+	case *utils.QueryAnd:
+		scopes, err := c.compileAll(n.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		return applyScopes(scopes), nil
+	case *utils.QueryOr:
+		scopes, err := c.compileAll(n.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		// 每个子条件单独成组，再用 OR 连接：(a) OR (b AND c)
+		return func(db *gorm.DB) *gorm.DB {
+			group := c.db.Session(&gorm.Session{NewDB: true})
+			for i, scope := range scopes {
+				condition := scope(c.db.Session(&gorm.Session{NewDB: true}))
+				if i == 0 {
+					group = group.Where(condition)
+				} else {
+					group = group.Or(condition)
+				}
+			}
+			return db.Where(group)
+		}, nil
+	case *utils.QueryNot:
+		scope, err := c.compile(n.Node)
+		if err != nil {
+			return nil, err
+		}
+		// 用子查询取反，列值为 NULL（如未分类）的文章也会被正确包含
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where("posts.id NOT IN (?)", scope(c.db.Model(&models.Post{}).Select("posts.id")))
+		}, nil
+	case *utils.QueryTerm:
+		return c.compileTerm(n)
+	}
+	return nil, fmt.Errorf("unknown query node %T", node)
+}
+
+func (c *postQueryCompiler) compileAll(nodes []utils.QueryNode) ([]postScope, error) {
+	scopes := make([]postScope, 0, len(nodes))
+	for _, node := range nodes {
+		scope, err := c.compile(node)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+func (c *postQueryCompiler) compileTerm(term *utils.QueryTerm) (postScope, error) {
+	switch term.Field {
+	case "":
+		return postContains(term.Value, "posts.title", "posts.content"), nil
+	case "title":
+		return postContains(term.Value, "posts.title"), nil
+	case "content":
+		return postContains(term.Value, "posts.content"), nil
+	case "author":
+		return postByAuthor(c.db, term.Value), nil
+	case "tag":
+		names := normalizeTags([]string{term.Value})
+		return postWithAnyTag(c.db, names), nil
+	case "category":
+		var category models.Category
+		if err := c.db.Where("slug = ?", term.Value).First(&category).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, termError(term, "Category not found")
+			}
+			return nil, err
+		}
+		return postInCategory(c.db, category.ID)
+	case "status":
+		switch term.Value {
+		case models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusPublished, models.PostStatusArchived:
+			return postWithStatus(term.Value), nil
+		}
+		return nil, termError(term, "Invalid status, expected draft, scheduled, published or archived")
+	case "comments":
+		comparisons, err := parseComparisons(term)
+		if err != nil {
+			return nil, err
+		}
+		scopes := make([]postScope, 0, len(comparisons))
+		for _, cmp := range comparisons {
+			n, err := strconv.ParseUint(cmp.value, 10, 32)
+			if err != nil {
+				return nil, termError(term, "Invalid number "+strconv.Quote(cmp.value))
+			}
+			scopes = append(scopes, postCommentNumber(cmp.op, uint(n)))
+		}
+		return applyScopes(scopes), nil
+	case "created":
+		comparisons, err := parseComparisons(term)
+		if err != nil {
+			return nil, err
+		}
+		var scopes []postScope
+		for _, cmp := range comparisons {
+			start, end, err := parseQueryDate(cmp.value)
+			if err != nil {
+				return nil, termError(term, "Invalid date "+strconv.Quote(cmp.value)+", expected YYYY, YYYY-MM or YYYY-MM-DD")
+			}
+			// 日期表示一个时间段 [start, end)
+			switch cmp.op {
+			case "=":
+				scopes = append(scopes, postCreatedAt(">=", start), postCreatedAt("<", end))
+			case ">":
+				scopes = append(scopes, postCreatedAt(">=", end))
+			case ">=":
+				scopes = append(scopes, postCreatedAt(">=", start))
+			case "<":
+				scopes = append(scopes, postCreatedAt("<", start))
+			case "<=":
+				scopes = append(scopes, postCreatedAt("<", end))
+			}
+		}
+		return applyScopes(scopes), nil
+	}
+	return nil, &utils.QueryError{Position: term.Position, Near: term.Field + ":", Message: "Unknown field " + strconv.Quote(term.Field)}
+}
+
+// 依次应用多个 scope。scope 执行时再调用 db.Scopes 不会生效，需要直接调用
+func applyScopes(scopes []postScope) postScope {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, scope := range scopes {
+			db = scope(db)
+		}
+		return db
+	}
+}
+
+func termError(term *utils.QueryTerm, message string) *utils.QueryError {
+	return &utils.QueryError{Position: term.ValuePosition, Near: term.Value, Message: message}
+}
+
+type comparison struct {
+	op    string // =、>、>=、<、<=
+	value string
+}
+
+// 解析比较：v、>v、>=v、<v、<=v，以及范围 a..b（含两端，可省略一端）
+func parseComparisons(term *utils.QueryTerm) ([]comparison, error) {
+	value := term.Value
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		if from == "" && to == "" {
+			return nil, termError(term, "Empty range")
+		}
+		var comparisons []comparison
+		if from != "" {
+			comparisons = append(comparisons, comparison{op: ">=", value: from})
+		}
+		if to != "" {
+			comparisons = append(comparisons, comparison{op: "<=", value: to})
+		}
+		return comparisons, nil
+	}
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(value, op); ok {
+			if rest == "" {
+				return nil, termError(term, "Missing value after "+op)
+			}
+			return []comparison{{op: op, value: rest}}, nil
+		}
+	}
+	return []comparison{{op: "=", value: value}}, nil
+}
+
+// 解析日期（服务器时区），返回所表示的时间段 [start, end)
+func parseQueryDate(value string) (time.Time, time.Time, error) {
+	for _, layout := range []struct {
+		format string
+		years  int
+		months int
+		days   int
+	}{
+		{"2006-01-02", 0, 0, 1},
+		{"2006-01", 0, 1, 0},
+		{"2006", 1, 0, 0},
+	} {
+		if start, err := time.ParseInLocation(layout.format, value, time.Local); err == nil {
+			return start, start.AddDate(layout.years, layout.months, layout.days), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// 以下条件由查询语言和条件查询（ListPostByCondition）共用
+
+// 任意一列包含 text（转义通配符）
+func postContains(text string, columns ...string) postScope {
+	return func(db *gorm.DB) *gorm.DB {
+		condition, args := likeAny(text, columns...)
+		return db.Where(condition, args...)
+	}
+}
+
+// 作者用户名
+func postByAuthor(db *gorm.DB, username string) postScope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("posts.user_id IN (?)", db.Model(&models.User{}).Select("id").Where("username = ?", username))
+	}
+}
+
+// 带有任意一个标签
+func postWithAnyTag(db *gorm.DB, names []string) postScope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("posts.id IN (?)", postIDsWithTags(db, names))
+	}
+}
+
+// 带有全部标签
+func postWithAllTags(db *gorm.DB, names []string) postScope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("posts.id IN (?)", postIDsWithTags(db, names).
+			Group("post_tags.post_id").
+			Having("COUNT(DISTINCT post_tags.tag_id) = ?", len(names)))
+	}
+}
+
+// 分类及其子分类，分类不存在时返回 404
+func postInCategory(db *gorm.DB, categoryID uint) (postScope, error) {
+	subtree, err := categorySubtree(db, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("posts.category_id IN (?)", subtree)
+	}, nil
+}
+
+func postWithStatus(status string) postScope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.status = ?", status)
+	}
+}
+
+// 评论数比较，op 只能是 comparison 中的运算符
+func postCommentNumber(op string, n uint) postScope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.comment_number "+op+" ?", n)
+	}
+}
+
+// 创建时间比较，op 只能是 comparison 中的运算符。created_at 以 utils.Time1 的格式保存，参数使用相同格式
+func postCreatedAt(op string, t time.Time) postScope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.created_at "+op+" ?", utils.Time1(t))
+	}
+}
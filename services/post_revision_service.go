@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 文章修订：修订历史、差异比较、回滚
+type PostRevisionService struct {
+	db *gorm.DB
+}
+
+func NewPostRevisionService(db *gorm.DB) *PostRevisionService {
+	return &PostRevisionService{db: db}
+}
+
+// 查询文章的修订历史（新的在前）。all 为 true 时可查询任意文章（版主）
+func (s *PostRevisionService) ListRevisions(userID, postID uint, all bool, pageNo, pageSize int) (*models.PagePostRevisionResponse, error) {
+	if _, err := s.findPost(userID, postID, all); err != nil {
+		return nil, err
+	}
+
+	var revisions []models.PostRevision
+	if err := s.db.Scopes(utils.Sql.Paginate(pageNo, pageSize)).
+		Where("post_id = ?", postID).
+		Order("version desc").
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	var total int64
+	if err := s.db.Model(&models.PostRevision{}).Where("post_id = ?", postID).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	summaries := make([]models.PostRevisionSummary, 0, len(revisions))
+	for _, revision := range revisions {
+		summaries = append(summaries, models.PostRevisionSummary{
+			Version:   revision.Version,
+			Title:     revision.Title,
+			Note:      revision.Note,
+			EditorID:  revision.EditorID,
+			CreatedAt: revision.CreatedAt,
+		})
+	}
+	return &models.PagePostRevisionResponse{Total: total, Revisions: summaries}, nil
+}
+
+// 查询文章的某个修订
+func (s *PostRevisionService) GetRevision(userID, postID, version uint, all bool) (*models.PostRevision, error) {
+	if _, err := s.findPost(userID, postID, all); err != nil {
+		return nil, err
+	}
+	return s.findRevision(postID, version)
+}
+
+// 比较文章的两个修订
+func (s *PostRevisionService) DiffRevisions(userID, postID, from, to uint, all bool) (*models.PostRevisionDiffResponse, error) {
+	if _, err := s.findPost(userID, postID, all); err != nil {
+		return nil, err
+	}
+	fromRevision, err := s.findRevision(postID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := s.findRevision(postID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := models.PostRevisionDiffResponse{
+		PostID:    postID,
+		From:      from,
+		To:        to,
+		FromTitle: fromRevision.Title,
+		ToTitle:   toRevision.Title,
+		Lines:     utils.DiffLines(fromRevision.Content, toRevision.Content),
+	}
+	for _, line := range diff.Lines {
+		switch line.Op {
+		case utils.DiffInsert:
+			diff.Additions++
+		case utils.DiffDelete:
+			diff.Deletions++
+		}
+	}
+	return &diff, nil
+}
+
+// 回滚到旧修订：用旧修订的标题和内容更新文章，并保存为新的修订（历史不会被改写）
+func (s *PostRevisionService) RestoreRevision(userID, postID, version uint) (*models.Post, error) {
+	post, err := s.findPost(userID, postID, false)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.findRevision(postID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(post).Updates(map[string]interface{}{
+			"title":   revision.Title,
+			"content": revision.Content,
+		}).Error; err != nil {
+			return err
+		}
+		post.Title, post.Content = revision.Title, revision.Content
+		return recordRevision(tx, post, userID, fmt.Sprintf("Restored from revision %d", version))
+	}); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (s *PostRevisionService) findPost(userID, postID uint, all bool) (*models.Post, error) {
+	tx := s.db.Where("id = ?", postID)
+	if !all {
+		tx = tx.Where("user_id = ?", userID)
+	}
+	var post models.Post
+	if err := tx.First(&post).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(404, "Post not exist")
+		}
+		return nil, err
+	}
+	return &post, nil
+}
+
+func (s *PostRevisionService) findRevision(postID, version uint) (*models.PostRevision, error) {
+	var revision models.PostRevision
+	if err := s.db.Where("post_id = ? AND version = ?", postID, version).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppErrorWithDetails(404, "Revision not found", map[string]uint{"version": version})
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// 将文章当前的标题和内容保存为新修订，版本号递增（需在事务中调用）。
+// (post_id, version) 唯一索引保证并发修改时不会产生重复版本
+func recordRevision(tx *gorm.DB, post *models.Post, editorID uint, note string) error {
+	var latest uint
+	if err := tx.Model(&models.PostRevision{}).
+		Where("post_id = ?", post.ID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.PostRevision{
+		PostID:   post.ID,
+		Version:  latest + 1,
+		Title:    post.Title,
+		Content:  post.Content,
+		Note:     note,
+		EditorID: editorID,
+	}).Error
+}
+
+// 修订功能上线前创建的文章没有修订：首次修改前先把原内容保存为第 1 个修订（需在事务中调用）
+func ensureBaseRevision(tx *gorm.DB, post *models.Post) error {
+	var count int64
+	if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return tx.Create(&models.PostRevision{
+		PostID:    post.ID,
+		Version:   1,
+		Title:     post.Title,
+		Content:   post.Content,
+		EditorID:  post.UserID,
+		CreatedAt: time.Time(post.UpdatedAt),
+	}).Error
+}
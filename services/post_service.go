@@ -1,6 +1,10 @@
 package services
 
 import (
+	"log"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 
 	"gin-examples/project/models"
@@ -17,6 +21,11 @@ func NewPostService(db *gorm.DB) *PostService {
 
 // 创建文章
 func (s *PostService) CreatePost(userId uint, req models.CreatePostRequest) (*models.Post, error) {
+	// 邮箱验证后才能发表文章
+	if err := requireVerifiedEmail(s.db, userId); err != nil {
+		return nil, err
+	}
+
 	// 检查标题是否已存在
 	var existingPost models.Post
 	if err := s.db.Where("title = ?", req.Title).First(&existingPost).Error; err == nil {
@@ -31,25 +40,56 @@ func (s *PostService) CreatePost(userId uint, req models.CreatePostRequest) (*mo
 	}
 	ctx := models.ContextWithValueAudit(container)
 
-	// 创建文章
+	// 创建文章：默认立即发布，指定了发布时间时默认定时发布
 	post := models.Post{
 		UserID:  userId,
 		Title:   req.Title,
 		Content: req.Content,
 	}
+	status := req.Status
+	if status == "" {
+		status = models.PostStatusPublished
+		if req.PublishAt != nil {
+			status = models.PostStatusScheduled
+		}
+	}
+	if err := applyPostStatus(&post, status, req.PublishAt); err != nil {
+		return nil, err
+	}
+	categoryID, err := resolvePostCategory(s.db, req.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	post.CategoryID = categoryID
 
-	if err := s.db.WithContext(ctx).Create(&post).Error; err != nil {
+	// 创建文章的同时保存标签和第 1 个修订
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&post).Error; err != nil {
+			return err
+		}
+		if len(req.Tags) > 0 {
+			if err := replacePostTags(tx, &post, req.Tags); err != nil {
+				return err
+			}
+		}
+		return recordRevision(tx, &post, userId, "")
+	}); err != nil {
 		return nil, err
 	}
 
 	return &post, nil
 }
 
-// 查询用户的全部文章
-func (s *PostService) ListPost(userId uint, pageNo, pageSize int) ([]models.Post, error) {
+// 查询用户的全部文章（含草稿等未发布的文章），status 不为空时只查询该状态
+func (s *PostService) ListPost(userId uint, status string, pageNo, pageSize int) ([]models.Post, error) {
 	var posts []models.Post
-	if err := s.db.Scopes(utils.Sql.Paginate(pageNo, pageSize), utils.Sql.OrderCreateAt()).
+	tx := s.db
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if err := tx.Scopes(utils.Sql.Paginate(pageNo, pageSize), utils.Sql.OrderCreateAt()).
 		Where("user_id = ?", userId).
+		Scopes(withTaxonomy).
 		// Order("created_at desc").
 		Find(&posts).Error; err != nil {
 		return nil, utils.NewAppError(409, "Query Post failed by userId")
@@ -57,17 +97,27 @@ func (s *PostService) ListPost(userId uint, pageNo, pageSize int) ([]models.Post
 	return posts, nil
 }
 
-// 查询所有用户的文章
-func (s *PostService) ListPostAll(pageNo, pageSize int) (*models.PagePostResponse, error) {
+// 查询所有用户的文章：已发布的文章，以及 viewerId（登录用户，匿名时为 0）自己未发布的文章。
+// q 不为空时按查询语言筛选，语法见 utils.ParseQuery，字段见 postQueryCompiler
+func (s *PostService) ListPostAll(viewerId uint, q string, pageNo, pageSize int) (*models.PagePostResponse, error) {
+	filter := func(db *gorm.DB) *gorm.DB { return db }
+	if strings.TrimSpace(q) != "" {
+		scope, err := compilePostQuery(s.db, q)
+		if err != nil {
+			return nil, err
+		}
+		filter = scope
+	}
+
 	var posts []models.Post
-	if err := s.db.Scopes(utils.Sql.Paginate(pageNo, pageSize), utils.Sql.OrderCreateAt()).
+	if err := s.db.Scopes(utils.Sql.Paginate(pageNo, pageSize), utils.Sql.OrderCreateAt(), visibleTo(viewerId), filter, withTaxonomy).
 		Where("audit_status", "active"). // 进查询 title 审计通过的
 		// Order("created_at desc").
 		Find(&posts).Error; err != nil {
 		return nil, utils.NewAppError(409, "Query Post failed")
 	}
 	var total int64
-	if err := s.db.Model(&models.Post{}).
+	if err := s.db.Model(&models.Post{}).Scopes(visibleTo(viewerId), filter).
 		Where("audit_status", "active"). // 进查询 title 审计通过的
 		Count(&total).Error; err != nil {
 		return nil, utils.NewAppError(409, "Query Post failed")
@@ -85,23 +135,40 @@ func (s *PostService) ListPostByCondition(req models.ListPostRequest) ([]models.
 	tx := s.db
 	// 动态拼接条件：创建时间范围（仅当Start和End都传了才筛选）
 	if req.CreatedAtStart != nil && req.CreatedAtEnd != nil {
-		tx = tx.Where("created_at BETWEEN ? AND ?", req.CreatedAtStart, req.CreatedAtEnd)
+		tx = tx.Scopes(postCreatedAt(">=", *req.CreatedAtStart), postCreatedAt("<=", *req.CreatedAtEnd))
 	}
 	// 动态拼接条件：最小评论数
 	if req.MinCommentNumber == nil {
-		tx = tx.Where("comment_number >= ?", 0)
+		tx = tx.Scopes(postCommentNumber(">=", 0))
 	} else {
-		tx = tx.Where("comment_number >= ?", req.MinCommentNumber)
+		tx = tx.Scopes(postCommentNumber(">=", *req.MinCommentNumber))
 	}
 	// 动态拼接条件：标题包含
 	if req.Title != "" {
-		tx = tx.Where("title like ?", "%"+req.Title+"%")
+		tx = tx.Scopes(postContains(req.Title, "posts.title"))
 	}
 	tx = tx.Where("audit_status", "active") // 进查询 title 审计通过的
+	tx = tx.Scopes(publishedPosts)
+	// 动态拼接条件：包含任意一个标签
+	if names := normalizeTags(req.TagsAny); len(names) > 0 {
+		tx = tx.Scopes(postWithAnyTag(s.db, names))
+	}
+	// 动态拼接条件：包含全部标签
+	if names := normalizeTags(req.TagsAll); len(names) > 0 {
+		tx = tx.Scopes(postWithAllTags(s.db, names))
+	}
+	// 动态拼接条件：分类及其子分类
+	if req.CategoryID != nil {
+		inCategory, err := postInCategory(s.db, *req.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Scopes(inCategory)
+	}
 	// SELECT * FROM `posts` WHERE comment_number >= 0 AND title >= "%hello%" AND `posts`.`deleted_at` IS NULL ORDER BY created_at desc LIMIT 10
 	// SELECT * FROM `posts` WHERE comment_number >= 2 AND title >= "%hello%" AND `posts`.`deleted_at` IS NULL ORDER BY created_at desc LIMIT 10
 	// SELECT * FROM `posts` WHERE (created_at BETWEEN "2026-01-10 12:39:35.35" AND "2026-01-16 15:05:28.322") AND comment_number >= 2 AND title >= "%hello%" AND `posts`.`deleted_at` IS NULL ORDER BY created_at desc LIMIT 10
-	if err := tx.Scopes(utils.Sql.Paginate(req.PageNo, req.PageSize), utils.Sql.OrderCreateAt()).
+	if err := tx.Scopes(utils.Sql.Paginate(req.PageNo, req.PageSize), utils.Sql.OrderCreateAt(), withTaxonomy).
 		// Where("title = ?", req.Title).
 		// Order("created_at desc").
 		Find(&posts).Error; err != nil {
@@ -110,8 +177,8 @@ func (s *PostService) ListPostByCondition(req models.ListPostRequest) ([]models.
 	return posts, nil
 }
 
-// 主键查询文章，关联查询最新两条评论
-func (s *PostService) GetPostById(id uint) (*models.Post, error) {
+// 主键查询文章，关联查询最新两条评论。未发布的文章只有作者（viewerId）可以查看
+func (s *PostService) GetPostById(id, viewerId uint) (*models.Post, error) {
 	// First with primary key
 	var post models.Post
 	// SELECT * FROM `posts` WHERE `posts`.`id` = 11 AND `posts`.`deleted_at` IS NULL ORDER BY `posts`.`id` LIMIT 1
@@ -120,7 +187,7 @@ func (s *PostService) GetPostById(id uint) (*models.Post, error) {
 		func(db *gorm.DB) *gorm.DB {
 			// return db.Scopes(utils.Sql.Paginate(1, 2)).Order("created_at desc")
 			return db.Scopes(utils.Sql.Paginate(1, 2), utils.Sql.OrderCreateAt())
-		}).First(&post, id).Error; err != nil {
+		}).Scopes(visibleTo(viewerId), withTaxonomy).First(&post, id).Error; err != nil {
 		return nil, utils.NewAppError(409, "Query Post failed by id")
 	}
 	return &post, nil
@@ -134,7 +201,7 @@ func (s *PostService) GetPostByMaxCommentNumber() (*models.Post, error) {
 		func(db *gorm.DB) *gorm.DB {
 			// return db.Scopes(utils.Sql.Paginate(1, 2)).Order("created_at desc")
 			return db.Scopes(utils.Sql.Paginate(1, 2), utils.Sql.OrderCreateAt())
-		}).Order("comment_number desc").First(&post).Error; err != nil {
+		}).Scopes(publishedPosts, withTaxonomy).Order("comment_number desc").First(&post).Error; err != nil {
 		return nil, utils.NewAppError(409, "Query max CommentNumber of Post failed")
 	}
 	return &post, nil
@@ -148,24 +215,206 @@ func (s *PostService) UpdatePost(userId uint, req models.UpdatePostRequest) (*mo
 		return nil, utils.NewAppError(409, "Post not exist")
 	}
 
+	previous := existingPost
 	existingPost.Title = req.Title
 	existingPost.Content = req.Content
+	if req.Status != "" {
+		if err := applyPostStatus(&existingPost, req.Status, req.PublishAt); err != nil {
+			return nil, err
+		}
+	}
+	if req.CategoryID != nil {
+		categoryID, err := resolvePostCategory(s.db, req.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		existingPost.CategoryID = categoryID
+	}
 
-	if err := s.db.Save(existingPost).Error; err != nil {
+	// 每次修改都保存一个修订，旧内容不会丢失
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureBaseRevision(tx, &previous); err != nil {
+			return err
+		}
+		if err := tx.Save(existingPost).Error; err != nil {
+			return err
+		}
+		if req.Tags != nil {
+			if err := replacePostTags(tx, &existingPost, req.Tags); err != nil {
+				return err
+			}
+		}
+		return recordRevision(tx, &existingPost, userId, req.Note)
+	}); err != nil {
 		return nil, err
 	}
 
 	return &existingPost, nil
 }
 
-// 删除用户的文章
-func (s *PostService) DeletePost(userId uint, id uint) (bool, error) {
-	// 检查标题是否已存在
+// 版主删除任意文章
+func (s *PostService) ModerateDeletePost(id uint) (bool, error) {
+	var existingPost models.Post
+	if err := s.db.First(&existingPost, id).Error; err != nil {
+		return false, utils.NewAppError(404, "Post not exist")
+	}
+	return s.DeletePost(existingPost.UserID, id)
+}
+
+// 版主修改文章审计状态
+func (s *PostService) UpdateAuditStatus(id uint, auditStatus string) (*models.Post, error) {
 	var existingPost models.Post
-	// physical delete by unscoped
-	// if err := s.db.Where("id = ? and user_id = ?", id, userId).Delete(&existingPost).Error; err != nil {
-	if err := s.db.Where("id = ? and user_id = ?", id, userId).Unscoped().Delete(&existingPost).Error; err != nil {
+	if err := s.db.First(&existingPost, id).Error; err != nil {
+		return nil, utils.NewAppError(404, "Post not exist")
+	}
+
+	existingPost.Audit.AuditStatus = auditStatus
+	if err := s.db.Model(&existingPost).Update("audit_status", auditStatus).Error; err != nil {
+		return nil, err
+	}
+	return &existingPost, nil
+}
+
+// 删除用户的文章：文章下的评论、文章的修订和标签关联一并删除，作者文章数 -1
+func (s *PostService) DeletePost(userId uint, id uint) (bool, error) {
+	deleted := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// physical delete by unscoped
+		// if err := s.db.Where("id = ? and user_id = ?", id, userId).Delete(&existingPost).Error; err != nil {
+		result := tx.Where("id = ? and user_id = ?", id, userId).Unscoped().Delete(&models.Post{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil // ID不存在，无数据被删除
+		}
+		deleted = true
+
+		// 批量删除评论时跳过评论删除钩子（钩子按单条评论维护评论数）
+		if err := tx.Session(&gorm.Session{SkipHooks: true}).Where("post_id = ?", id).Unscoped().Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_id = ?", id).Delete(&models.PostRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_tags WHERE post_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ? AND post_number > 0", userId).
+			UpdateColumn("post_number", gorm.Expr("post_number - 1")).Error
+	})
+	if err != nil {
 		return false, utils.NewAppError(409, "Post delete failed")
 	}
-	return true, nil
+	return deleted, nil
+}
+
+// 设置文章状态：定时发布要求发布时间晚于当前时间，首次发布时记录发布时间
+func applyPostStatus(post *models.Post, status string, publishAt *time.Time) error {
+	now := time.Now()
+	switch status {
+	case models.PostStatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return utils.NewAppError(400, "publish_at must be in the future")
+		}
+		post.PublishAt = publishAt
+	case models.PostStatusPublished:
+		post.PublishAt = nil
+		if post.PublishedAt == nil {
+			post.PublishedAt = &now
+		}
+	default:
+		post.PublishAt = nil
+	}
+	post.Status = status
+	return nil
+}
+
+// 关联查询文章的标签和分类
+func withTaxonomy(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("tags.name")
+	}).Preload("Category")
+}
+
+// 带有指定标签（任意一个）的文章 id
+func postIDsWithTags(db *gorm.DB, names []string) *gorm.DB {
+	return db.Table("post_tags").
+		Select("post_tags.post_id").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Where("tags.name IN ?", names)
+}
+
+// 只查询已发布的文章
+func publishedPosts(db *gorm.DB) *gorm.DB {
+	return db.Where("posts.status = ?", models.PostStatusPublished)
+}
+
+// 已发布的文章，以及 viewerId 自己的文章（viewerId 为 0 表示匿名访问）
+func visibleTo(viewerId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerId == 0 {
+			return publishedPosts(db)
+		}
+		return db.Where("(posts.status = ? OR posts.user_id = ?)", models.PostStatusPublished, viewerId)
+	}
+}
+
+// 发布到期的定时文章。逐篇条件更新（仍为 scheduled 且已到期），多个实例同时运行时每篇文章也只发布一次；
+// 状态保存在数据库中，重启后补发停机期间到期的文章
+func (s *PostService) PublishDuePosts() (int, error) {
+	var due []models.Post
+	if err := s.db.Select("id").
+		Where("status = ? AND publish_at <= ?", models.PostStatusScheduled, time.Now()).
+		Order("publish_at").
+		Limit(100).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, post := range due {
+		result := s.db.Model(&models.Post{}).
+			Where("id = ? AND status = ? AND publish_at <= ?", post.ID, models.PostStatusScheduled, time.Now()).
+			UpdateColumns(map[string]interface{}{
+				"status":       models.PostStatusPublished,
+				"published_at": gorm.Expr("publish_at"),
+			})
+		if result.Error != nil {
+			return published, result.Error
+		}
+		if result.RowsAffected == 1 {
+			log.Printf("scheduled post %d published", post.ID)
+			published++
+		}
+	}
+	return published, nil
+}
+
+// 定时发布调度器：启动时立即补发一次，之后每隔 interval 检查一次
+func (s *PostService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.PublishDuePosts(); err != nil {
+				log.Printf("publish scheduled posts failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// 按现有评论重新统计文章的评论数和评论状态（需在事务中调用）
+func recountComments(tx *gorm.DB, postIDs []uint) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	count := tx.Model(&models.Comment{}).Select("COUNT(*)").Where("comments.post_id = posts.id")
+	if err := tx.Model(&models.Post{}).Where("id IN ?", postIDs).UpdateColumn("comment_number", count).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Post{}).Where("id IN ?", postIDs).
+		UpdateColumn("comment_status", gorm.Expr("CASE WHEN comment_number > 0 THEN ? ELSE ? END", "热评中", "无评论")).Error
 }
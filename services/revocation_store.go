@@ -0,0 +1,103 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gin-examples/project/models"
+)
+
+// 访问令牌注销列表：按 jti 记录，过期后自动清理
+type RevocationStore interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+	PruneExpired() error
+}
+
+// 根据配置创建注销列表：memory 只适合单实例部署，database 可多实例共享
+func NewRevocationStore(kind string, db *gorm.DB) RevocationStore {
+	if kind == "memory" {
+		return NewMemoryRevocationStore()
+	}
+	return NewGormRevocationStore(db)
+}
+
+// 定时清理过期记录
+func StartRevocationPruner(store RevocationStore, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := store.PruneExpired(); err != nil {
+				log.Printf("prune revoked tokens failed: %v", err)
+			}
+		}
+	}()
+}
+
+// 内存实现
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revoked: make(map[string]time.Time)}
+}
+
+func (s *MemoryRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked[jti] = expiresAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.revoked[jti]
+	return ok, nil
+}
+
+func (s *MemoryRevocationStore) PruneExpired() error {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for jti, expiresAt := range s.revoked {
+		if now.After(expiresAt) {
+			delete(s.revoked, jti)
+		}
+	}
+	return nil
+}
+
+// GORM 实现
+type GormRevocationStore struct {
+	db *gorm.DB
+}
+
+func NewGormRevocationStore(db *gorm.DB) *GormRevocationStore {
+	return &GormRevocationStore{db: db}
+}
+
+func (s *GormRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	// 重复注销同一个令牌时忽略
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (s *GormRevocationStore) IsRevoked(jti string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *GormRevocationStore) PruneExpired() error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 内置权限及说明
+var defaultPermissions = []models.Permission{
+	{Code: models.PermPostsModerate, Description: "删除任意文章、修改文章审计状态"},
+	{Code: models.PermCommentsModerate, Description: "删除任意评论"},
+	{Code: models.PermUsersRead, Description: "查看用户列表"},
+	{Code: models.PermUsersManage, Description: "管理用户"},
+	{Code: models.PermRolesManage, Description: "分配角色、修改角色权限"},
+	{Code: models.PermInvitesManage, Description: "不受配额限制地创建邀请码、管理全部邀请码"},
+	{Code: models.PermCategoriesManage, Description: "创建、修改、删除文章分类"},
+}
+
+// 内置角色及其初始权限（仅在角色首次创建时写入，之后以数据库为准）
+var defaultRoles = []struct {
+	Name        string
+	Description string
+	Permissions []string
+}{
+	{models.RoleUser, "普通用户", nil},
+	{models.RoleModerator, "版主", []string{models.PermPostsModerate, models.PermCommentsModerate, models.PermUsersRead}},
+	{models.RoleAdmin, "管理员", []string{models.PermPostsModerate, models.PermCommentsModerate, models.PermUsersRead, models.PermUsersManage, models.PermRolesManage, models.PermInvitesManage, models.PermCategoriesManage}},
+}
+
+type RoleService struct {
+	db *gorm.DB
+}
+
+func NewRoleService(db *gorm.DB) *RoleService {
+	return &RoleService{db: db}
+}
+
+// 初始化内置角色和权限（可重复执行）
+// 新版本引入的权限会授予已存在的内置角色，其余权限以数据库为准
+func (s *RoleService) SeedDefaultRoles() error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		added := make(map[string]models.Permission)
+		for _, p := range defaultPermissions {
+			perm := p
+			result := tx.Where("code = ?", perm.Code).FirstOrCreate(&perm)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				added[perm.Code] = perm
+			}
+		}
+
+		for _, r := range defaultRoles {
+			var role models.Role
+			err := tx.Where("name = ?", r.Name).First(&role).Error
+			if err == nil {
+				var grants []models.Permission
+				for _, code := range r.Permissions {
+					if perm, ok := added[code]; ok {
+						grants = append(grants, perm)
+					}
+				}
+				if len(grants) > 0 {
+					if err := tx.Model(&role).Association("Permissions").Append(grants); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			var perms []models.Permission
+			if len(r.Permissions) > 0 {
+				if err := tx.Where("code IN ?", r.Permissions).Find(&perms).Error; err != nil {
+					return err
+				}
+			}
+			role = models.Role{Name: r.Name, Description: r.Description, Permissions: perms}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 查询全部角色及权限
+func (s *RoleService) ListRoles() ([]models.Role, error) {
+	var roles []models.Role
+	if err := s.db.Preload("Permissions").Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// 替换角色的权限
+func (s *RoleService) UpdateRolePermissions(name string, codes []string) (*models.Role, error) {
+	var role models.Role
+	if err := s.db.Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(404, "Role not found")
+		}
+		return nil, err
+	}
+
+	// 重复的权限只算一次
+	codes = slices.Compact(slices.Sorted(slices.Values(codes)))
+	var perms []models.Permission
+	if len(codes) > 0 {
+		if err := s.db.Where("code IN ?", codes).Find(&perms).Error; err != nil {
+			return nil, err
+		}
+		if len(perms) != len(codes) {
+			return nil, utils.NewAppError(400, "Unknown permission")
+		}
+	}
+
+	// 令牌中带有角色的权限，修改后该角色用户的访问令牌版本 +1 使其失效；刷新令牌仍可用，刷新后得到新的权限
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&role).Association("Permissions").Replace(perms); err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("role = ?", role.Name).
+			UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+	}); err != nil {
+		return nil, err
+	}
+	role.Permissions = perms
+	return &role, nil
+}
+
+// 初始化管理员：系统中还没有管理员时，把指定用户设为 admin（配置 auth.bootstrap_admin，启动时调用）。
+// 已有管理员时不做任何修改，之后由管理员通过接口分配角色
+func (s *RoleService) BootstrapAdmin(username string) (bool, error) {
+	if username == "" {
+		return false, nil
+	}
+	var admins int64
+	if err := s.db.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+		return false, err
+	}
+	if admins > 0 {
+		return false, nil
+	}
+
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, utils.NewAppError(404, "User not found")
+		}
+		return false, err
+	}
+	if _, err := s.UpdateUserRole(user.ID, models.RoleAdmin); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 分配用户角色：旧令牌中的角色已过期，令牌版本 +1 使其失效
+func (s *RoleService) UpdateUserRole(userID uint, roleName string) (*models.User, error) {
+	var role models.Role
+	if err := s.db.Where("name = ?", roleName).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(400, "Role not found")
+		}
+		return nil, err
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewAppError(404, "User not found")
+			}
+			return err
+		}
+		if err := tx.Model(&user).Update("role", role.Name).Error; err != nil {
+			return err
+		}
+		return revokeUserTokens(tx, user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// 查询角色拥有的权限编码
+func rolePermissions(db *gorm.DB, roleName string) ([]string, error) {
+	var codes []string
+	if err := db.Table("permissions").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ?", roleName).
+		Order("permissions.code").
+		Pluck("permissions.code", &codes).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
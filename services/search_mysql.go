@@ -0,0 +1,88 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ngram 解析器默认 ngram_token_size = 2，短于 2 个字符的搜索词改用 LIKE 匹配
+const ngramMinLength = 2
+
+// MySQL FULLTEXT 索引（ngram 解析器，支持中文），由 InnoDB 随写入自动维护
+var mysqlSearchIndexes = []struct {
+	table, name, columns string
+}{
+	{"posts", "ft_posts_title_content", "title, content"},
+	{"comments", "ft_comments_content", "content"},
+}
+
+// MySQL FULLTEXT 搜索，相关度为 MATCH ... AGAINST 的得分（标题匹配额外加权）
+type mysqlSearcher struct {
+	db *gorm.DB
+}
+
+// 创建缺失的 FULLTEXT 索引
+func (s *mysqlSearcher) Setup() error {
+	for _, index := range mysqlSearchIndexes {
+		var count int64
+		if err := s.db.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+			index.table, index.name).Scan(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		if err := s.db.Exec("CREATE FULLTEXT INDEX " + index.name + " ON " + index.table + " (" + index.columns + ") WITH PARSER ngram").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *mysqlSearcher) SearchPosts(terms []string, pageNo, pageSize int) ([]SearchRow, int64, error) {
+	against, short := ngramAgainst(terms)
+	if against == "" {
+		return (&likeSearcher{db: s.db}).SearchPosts(terms, pageNo, pageSize)
+	}
+	query := searchablePosts(s.db).
+		Where("MATCH(posts.title, posts.content) AGAINST(? IN BOOLEAN MODE)", against)
+	for _, term := range short {
+		condition, args := likeAny(term, "posts.title", "posts.content")
+		query = query.Where(condition, args...)
+	}
+	// FULLTEXT 索引同时覆盖标题和内容，标题命中时再加一次 LIKE 权重
+	titleScore, titleArgs := likeScore(terms, []weightedColumn{{"posts.title", 2}})
+	score := "MATCH(posts.title, posts.content) AGAINST(? IN BOOLEAN MODE) + " + titleScore
+	return runSearch(query, postSearchColumns, score, append([]interface{}{against}, titleArgs...), "posts.created_at", pageNo, pageSize)
+}
+
+func (s *mysqlSearcher) SearchComments(terms []string, pageNo, pageSize int) ([]SearchRow, int64, error) {
+	against, short := ngramAgainst(terms)
+	if against == "" {
+		return (&likeSearcher{db: s.db}).SearchComments(terms, pageNo, pageSize)
+	}
+	query := searchableComments(s.db).
+		Where("MATCH(comments.content) AGAINST(? IN BOOLEAN MODE)", against)
+	for _, term := range short {
+		condition, args := likeAny(term, "comments.content")
+		query = query.Where(condition, args...)
+	}
+	return runSearch(query, commentSearchColumns, "MATCH(comments.content) AGAINST(? IN BOOLEAN MODE)", []interface{}{against},
+		"comments.created_at", pageNo, pageSize)
+}
+
+// BOOLEAN MODE 查询表达式：每个搜索词作为必须出现的短语；返回过短、需要用 LIKE 匹配的搜索词
+func ngramAgainst(terms []string) (string, []string) {
+	var phrases, short []string
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < ngramMinLength {
+			short = append(short, term)
+			continue
+		}
+		// 短语中不能包含双引号，替换为空格（ngram 会跨过空白切分）
+		phrases = append(phrases, `+"`+strings.ReplaceAll(term, `"`, " ")+`"`)
+	}
+	return strings.Join(phrases, " "), short
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+const (
+	searchMaxQueryLength = 100 // 搜索词总长度上限（字符）
+	searchMaxTerms       = 10  // 搜索词个数上限
+	searchSnippetLength  = 120 // 内容片段长度（字符）
+)
+
+// 全文搜索：按相关度排序，只搜索已发布且审核通过的文章及其评论。
+// 索引由数据库维护（SQLite 触发器、MySQL FULLTEXT），文章、评论的任何写入方式都会同步到索引
+type Searcher interface {
+	// 建立索引（启动时调用，可重复执行）
+	Setup() error
+	// 搜索文章：全部搜索词都要出现在标题或内容中
+	SearchPosts(terms []string, pageNo, pageSize int) ([]SearchRow, int64, error)
+	// 搜索评论：全部搜索词都要出现在评论内容中
+	SearchComments(terms []string, pageNo, pageSize int) ([]SearchRow, int64, error)
+}
+
+// 搜索结果行，高亮前的原文（供 Searcher 的实现返回）
+type SearchRow struct {
+	ID        uint
+	PostID    uint
+	Title     string
+	Content   string
+	Score     float64
+	CreatedAt time.Time
+}
+
+// 按数据库类型选择搜索实现：SQLite 使用 FTS5（需要以 sqlite_fts5 构建标签编译驱动），MySQL 使用 FULLTEXT，
+// 不支持时退化为 LIKE 匹配
+func NewSearcher(db *gorm.DB) Searcher {
+	switch db.Dialector.Name() {
+	case "sqlite":
+		if sqliteFTS5Enabled(db) {
+			return &sqliteSearcher{db: db}
+		}
+		log.Println("Warning: SQLite is built without FTS5 (build with -tags sqlite_fts5), search falls back to LIKE")
+	case "mysql":
+		return &mysqlSearcher{db: db}
+	}
+	return &likeSearcher{db: db}
+}
+
+// 搜索
+type SearchService struct {
+	searcher Searcher
+}
+
+func NewSearchService(searcher Searcher) *SearchService {
+	return &SearchService{searcher: searcher}
+}
+
+// 搜索文章或评论，q 按空白分为多个搜索词
+func (s *SearchService) Search(q, searchType string, pageNo, pageSize int) (*models.SearchResponse, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, utils.NewAppError(400, "Search query required")
+	}
+	if utf8.RuneCountInString(q) > searchMaxQueryLength {
+		return nil, utils.NewAppErrorWithDetails(400, "Search query too long", map[string]int{"max_length": searchMaxQueryLength})
+	}
+	terms := searchTerms(q)
+	if len(terms) > searchMaxTerms {
+		return nil, utils.NewAppErrorWithDetails(400, "Too many search terms", map[string]int{"max_terms": searchMaxTerms})
+	}
+
+	var rows []SearchRow
+	var total int64
+	var err error
+	switch searchType {
+	case models.SearchTypePost:
+		rows, total, err = s.searcher.SearchPosts(terms, pageNo, pageSize)
+	case models.SearchTypeComment:
+		rows, total, err = s.searcher.SearchComments(terms, pageNo, pageSize)
+	default:
+		return nil, utils.NewAppError(400, "Invalid search type")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]models.SearchHit, 0, len(rows))
+	for _, row := range rows {
+		hits = append(hits, models.SearchHit{
+			Type:      searchType,
+			ID:        row.ID,
+			PostID:    row.PostID,
+			Title:     utils.Highlight(row.Title, terms, 0),
+			Snippet:   utils.Highlight(row.Content, terms, searchSnippetLength),
+			Score:     row.Score,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	return &models.SearchResponse{Query: q, Type: searchType, Total: total, Hits: hits}, nil
+}
+
+// 按空白切分搜索词，去重（不区分大小写）
+func searchTerms(q string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range strings.Fields(q) {
+		key := strings.ToLower(term)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// LIKE 匹配：没有全文索引时使用，相关度为搜索词在标题（权重 2）、内容（权重 1）中出现的加权和
+type likeSearcher struct {
+	db *gorm.DB
+}
+
+func (s *likeSearcher) Setup() error {
+	// 之前以 FTS5 运行过的数据库留有同步索引的触发器，没有 FTS5 时写入会失败，需要删除
+	if s.db.Dialector.Name() == "sqlite" {
+		return dropSQLiteSearchTriggers(s.db)
+	}
+	return nil
+}
+
+func (s *likeSearcher) SearchPosts(terms []string, pageNo, pageSize int) ([]SearchRow, int64, error) {
+	query := searchablePosts(s.db)
+	for _, term := range terms {
+		condition, args := likeAny(term, "posts.title", "posts.content")
+		query = query.Where(condition, args...)
+	}
+	score, args := likeScore(terms, []weightedColumn{{"posts.title", 2}, {"posts.content", 1}})
+	return runSearch(query, postSearchColumns, score, args, "posts.created_at", pageNo, pageSize)
+}
+
+func (s *likeSearcher) SearchComments(terms []string, pageNo, pageSize int) ([]SearchRow, int64, error) {
+	query := searchableComments(s.db)
+	for _, term := range terms {
+		condition, args := likeAny(term, "comments.content")
+		query = query.Where(condition, args...)
+	}
+	score, args := likeScore(terms, []weightedColumn{{"comments.content", 1}})
+	return runSearch(query, commentSearchColumns, score, args, "comments.created_at", pageNo, pageSize)
+}
+
+const (
+	postSearchColumns    = "posts.id AS id, posts.id AS post_id, posts.title AS title, posts.content AS content, posts.created_at AS created_at"
+	commentSearchColumns = "comments.id AS id, comments.post_id AS post_id, posts.title AS title, comments.content AS content, comments.created_at AS created_at"
+)
+
+// 可搜索的文章：已发布、审核通过、未删除
+func searchablePosts(db *gorm.DB) *gorm.DB {
+	return db.Table("posts").
+		Where("posts.deleted_at IS NULL AND posts.status = ? AND posts.audit_status = ?", models.PostStatusPublished, "active")
+}
+
+// 可搜索的评论：所属文章可搜索
+func searchableComments(db *gorm.DB) *gorm.DB {
+	return db.Table("comments").
+		Joins("JOIN posts ON posts.id = comments.post_id").
+		Where("comments.deleted_at IS NULL").
+		Where("posts.deleted_at IS NULL AND posts.status = ? AND posts.audit_status = ?", models.PostStatusPublished, "active")
+}
+
+// 统计总数并按相关度、时间倒序分页查询
+func runSearch(query *gorm.DB, columns, score string, scoreArgs []interface{}, createdAt string, pageNo, pageSize int) ([]SearchRow, int64, error) {
+	query = query.Session(&gorm.Session{})
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var rows []SearchRow
+	if err := query.Select(columns+", "+score+" AS score", scoreArgs...).
+		Order("score desc").
+		Order(createdAt + " desc").
+		Scopes(utils.Sql.Paginate(pageNo, pageSize)).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
+
+// 任意一列包含 term
+func likeAny(term string, columns ...string) (string, []interface{}) {
+	conditions := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		conditions = append(conditions, column+" LIKE ? ESCAPE '!'")
+		args = append(args, likePattern(term))
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+type weightedColumn struct {
+	column string
+	weight int
+}
+
+// LIKE 相关度：每个搜索词在每列中出现时加上该列的权重
+func likeScore(terms []string, columns []weightedColumn) (string, []interface{}) {
+	var parts []string
+	var args []interface{}
+	for _, term := range terms {
+		for _, c := range columns {
+			parts = append(parts, fmt.Sprintf("CASE WHEN %s LIKE ? ESCAPE '!' THEN %d ELSE 0 END", c.column, c.weight))
+			args = append(args, likePattern(term))
+		}
+	}
+	return "(" + strings.Join(parts, " + ") + ")", args
+}
+
+// LIKE 通配符转义（以 ! 为转义符，SQLite、MySQL 通用），查询条件需带上 ESCAPE '!'
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// 包含 term 的 LIKE 模式
+func likePattern(term string) string {
+	return "%" + likeEscaper.Replace(term) + "%"
+}
+
+// 以 prefix 开头的 LIKE 模式
+func likePrefixPattern(prefix string) string {
+	return likeEscaper.Replace(prefix) + "%"
+}
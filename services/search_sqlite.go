@@ -0,0 +1,128 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// trigram 分词器按 3 个字符切分，可用于中文等没有空格分词的文本；短于 3 个字符的搜索词无法使用索引，改用 LIKE 匹配
+const trigramMinLength = 3
+
+// SQLite FTS5 全文索引：posts_fts、comments_fts 为外部内容表（不重复保存文本），由触发器与 posts、comments 同步
+var sqliteSearchSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(title, content, content='posts', content_rowid='id', tokenize='trigram')`,
+	`CREATE TRIGGER IF NOT EXISTS posts_fts_ai AFTER INSERT ON posts BEGIN
+		INSERT INTO posts_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS posts_fts_ad AFTER DELETE ON posts BEGIN
+		INSERT INTO posts_fts(posts_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS posts_fts_au AFTER UPDATE OF title, content ON posts BEGIN
+		INSERT INTO posts_fts(posts_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+		INSERT INTO posts_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+	END`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(content, content='comments', content_rowid='id', tokenize='trigram')`,
+	`CREATE TRIGGER IF NOT EXISTS comments_fts_ai AFTER INSERT ON comments BEGIN
+		INSERT INTO comments_fts(rowid, content) VALUES (new.id, new.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS comments_fts_ad AFTER DELETE ON comments BEGIN
+		INSERT INTO comments_fts(comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS comments_fts_au AFTER UPDATE OF content ON comments BEGIN
+		INSERT INTO comments_fts(comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+		INSERT INTO comments_fts(rowid, content) VALUES (new.id, new.content);
+	END`,
+}
+
+var sqliteSearchTriggers = []string{"posts_fts_ai", "posts_fts_ad", "posts_fts_au", "comments_fts_ai", "comments_fts_ad", "comments_fts_au"}
+
+// SQLite 驱动是否编译了 FTS5
+func sqliteFTS5Enabled(db *gorm.DB) bool {
+	var enabled int
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return false
+	}
+	return enabled == 1
+}
+
+func dropSQLiteSearchTriggers(db *gorm.DB) error {
+	for _, name := range sqliteSearchTriggers {
+		if err := db.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SQLite FTS5 搜索，相关度为 bm25（标题权重 10，内容权重 1）
+type sqliteSearcher struct {
+	db *gorm.DB
+}
+
+// 创建索引表和触发器。触发器缺失时（首次启动，或期间以不带 FTS5 的版本运行过）重建索引，补上缺失的数据
+func (s *sqliteSearcher) Setup() error {
+	var triggers int64
+	if err := s.db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ?", sqliteSearchTriggers).
+		Scan(&triggers).Error; err != nil {
+		return err
+	}
+	if triggers == int64(len(sqliteSearchTriggers)) {
+		return nil
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range sqliteSearchSchema {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("INSERT INTO posts_fts(posts_fts) VALUES ('rebuild')").Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO comments_fts(comments_fts) VALUES ('rebuild')").Error
+	})
+}
+
+func (s *sqliteSearcher) SearchPosts(terms []string, pageNo, pageSize int) ([]SearchRow, int64, error) {
+	match, short := trigramMatch(terms)
+	if match == "" {
+		return (&likeSearcher{db: s.db}).SearchPosts(terms, pageNo, pageSize)
+	}
+	query := searchablePosts(s.db).
+		Joins("JOIN posts_fts ON posts_fts.rowid = posts.id").
+		Where("posts_fts MATCH ?", match)
+	for _, term := range short {
+		condition, args := likeAny(term, "posts.title", "posts.content")
+		query = query.Where(condition, args...)
+	}
+	return runSearch(query, postSearchColumns, "-bm25(posts_fts, 10.0, 1.0)", nil, "posts.created_at", pageNo, pageSize)
+}
+
+func (s *sqliteSearcher) SearchComments(terms []string, pageNo, pageSize int) ([]SearchRow, int64, error) {
+	match, short := trigramMatch(terms)
+	if match == "" {
+		return (&likeSearcher{db: s.db}).SearchComments(terms, pageNo, pageSize)
+	}
+	query := searchableComments(s.db).
+		Joins("JOIN comments_fts ON comments_fts.rowid = comments.id").
+		Where("comments_fts MATCH ?", match)
+	for _, term := range short {
+		condition, args := likeAny(term, "comments.content")
+		query = query.Where(condition, args...)
+	}
+	return runSearch(query, commentSearchColumns, "-bm25(comments_fts)", nil, "comments.created_at", pageNo, pageSize)
+}
+
+// FTS5 查询表达式：每个搜索词作为短语（双引号转义），全部匹配；返回过短、需要用 LIKE 匹配的搜索词
+func trigramMatch(terms []string) (string, []string) {
+	var phrases, short []string
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < trigramMinLength {
+			short = append(short, term)
+			continue
+		}
+		phrases = append(phrases, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(phrases, " AND "), short
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 最近活跃时间的更新间隔，避免每个请求都写数据库
+const sessionTouchInterval = time.Minute
+
+// 登录会话：查询、撤销
+type SessionService struct {
+	db *gorm.DB
+}
+
+func NewSessionService(db *gorm.DB) *SessionService {
+	return &SessionService{db: db}
+}
+
+// 查询用户的有效会话，currentID 为发起请求的会话
+func (s *SessionService) ListSessions(userID, currentID uint) ([]models.SessionResponse, error) {
+	var sessions []models.Session
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, models.SessionResponse{Session: session, Current: session.ID == currentID})
+	}
+	return responses, nil
+}
+
+// 撤销会话：该会话的访问令牌、刷新令牌随即失效
+func (s *SessionService) RevokeSession(userID, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		revoked, err := revokeSession(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return utils.NewAppError(404, "Session not found")
+		}
+		return nil
+	})
+}
+
+// 登录时创建会话
+func createSession(tx *gorm.DB, userID uint, familyID, userAgent, ip string, expiresAt time.Time) (*models.Session, error) {
+	session := models.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		Device:     utils.DescribeUserAgent(userAgent),
+		UserAgent:  truncate(userAgent, 512),
+		IP:         ip,
+		ExpiresAt:  expiresAt,
+		LastSeenAt: time.Now(),
+	}
+	if err := tx.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// 撤销单个会话及其刷新令牌族，会话不存在或已撤销时返回 false
+func revokeSession(tx *gorm.DB, userID, id uint) (bool, error) {
+	var session models.Session
+	if err := tx.Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	now := time.Now()
+	if err := tx.Model(&session).Update("revoked_at", now).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", session.FamilyID).
+		Update("revoked_at", now).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 撤销用户的全部会话（需在事务中调用）
+func revokeSessions(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// 校验访问令牌所属的会话未被撤销，并更新最近活跃时间
+func checkSession(db *gorm.DB, userID, sessionID uint) error {
+	var session models.Session
+	if err := db.Select("id", "user_id", "last_seen_at", "revoked_at").First(&session, sessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(401, "Session revoked")
+		}
+		return err
+	}
+	if session.UserID != userID || session.RevokedAt != nil {
+		return utils.NewAppError(401, "Session revoked")
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := db.Model(&session).UpdateColumn("last_seen_at", now).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
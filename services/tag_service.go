@@ -0,0 +1,82 @@
+package services
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 标签：侧边栏的标签列表
+type TagService struct {
+	db *gorm.DB
+}
+
+func NewTagService(db *gorm.DB) *TagService {
+	return &TagService{db: db}
+}
+
+// 查询标签及已发布文章数（文章多的在前），prefix 不为空时按前缀过滤；没有已发布文章的标签不返回
+func (s *TagService) ListTags(prefix string, pageNo, pageSize int) (*models.PageTagResponse, error) {
+	query := s.db.Table("tags").
+		Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL").
+		Where("posts.status = ? AND posts.audit_status = ?", models.PostStatusPublished, "active")
+	if prefix = strings.ToLower(strings.TrimSpace(prefix)); prefix != "" {
+		query = query.Where("tags.name LIKE ? ESCAPE '!'", likePrefixPattern(prefix))
+	}
+	query = query.Session(&gorm.Session{})
+
+	tags := []models.TagCount{}
+	if err := query.Select("tags.name AS name, COUNT(DISTINCT posts.id) AS post_count").
+		Group("tags.id, tags.name").
+		Order("post_count desc, tags.name").
+		Scopes(utils.Sql.Paginate(pageNo, pageSize)).
+		Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+	var total int64
+	if err := query.Distinct("tags.id").Count(&total).Error; err != nil {
+		return nil, err
+	}
+	return &models.PageTagResponse{Total: total, Tags: tags}, nil
+}
+
+// 规范化标签名：去掉首尾空白、转为小写、去重，忽略空标签
+func normalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	return normalized
+}
+
+// 按名称查询标签，不存在的自动创建（需在事务中调用）
+func findOrCreateTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range normalizeTags(names) {
+		tag := models.Tag{Name: name}
+		if err := tx.Where("name = ?", name).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// 替换文章的标签（需在事务中调用）
+func replacePostTags(tx *gorm.DB, post *models.Post, names []string) error {
+	tags, err := findOrCreateTags(tx, names)
+	if err != nil {
+		return err
+	}
+	return tx.Model(post).Association("Tags").Replace(tags)
+}
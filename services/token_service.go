@@ -0,0 +1,313 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 刷新令牌被重放
+var errRefreshTokenReused = utils.NewAppError(401, "Refresh token reuse detected")
+
+type TokenService struct {
+	db            *gorm.DB
+	jwtOptions    *utils.JWTOptions
+	refreshExpire time.Duration
+	revocations   RevocationStore
+}
+
+func NewTokenService(db *gorm.DB, jwtOptions *utils.JWTOptions, refreshExpire time.Duration, revocations RevocationStore) *TokenService {
+	return &TokenService{
+		db:            db,
+		jwtOptions:    jwtOptions,
+		refreshExpire: refreshExpire,
+		revocations:   revocations,
+	}
+}
+
+// 登录成功后签发访问令牌和刷新令牌：开启一个新的令牌族，并记录登录会话
+func (s *TokenService) IssueTokens(user *models.User, userAgent, ip string) (*models.TokenPair, error) {
+	familyID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	var pair *models.TokenPair
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		session, err := createSession(tx, user.ID, familyID, userAgent, ip, time.Now().Add(s.refreshExpire))
+		if err != nil {
+			return err
+		}
+		pair, err = s.issueTokens(tx, user, session)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// 使用刷新令牌换取新的令牌对，旧的刷新令牌随即失效
+// 已轮换过的刷新令牌被再次使用，说明令牌可能已泄露，整个令牌族全部作废
+func (s *TokenService) RefreshTokens(refreshToken string) (*models.TokenPair, error) {
+	var existing models.RefreshToken
+	if err := s.db.Where("token_hash = ?", utils.HashToken(refreshToken)).First(&existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(401, "Invalid refresh token")
+		}
+		return nil, err
+	}
+
+	if existing.RevokedAt != nil {
+		return nil, utils.NewAppError(401, "Refresh token revoked")
+	}
+	if existing.UsedAt != nil {
+		if err := s.RevokeFamily(existing.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errRefreshTokenReused
+	}
+	if time.Now().After(existing.ExpiresAt) {
+		return nil, utils.NewAppError(401, "Refresh token expired")
+	}
+
+	var pair *models.TokenPair
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新：并发刷新时只有一个请求能轮换成功
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", existing.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		var user models.User
+		if err := tx.First(&user, existing.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewAppError(401, "User not found")
+			}
+			return err
+		}
+
+		session, err := familySession(tx, &existing)
+		if err != nil {
+			return err
+		}
+		pair, err = s.issueTokens(tx, &user, session)
+		if err != nil {
+			return err
+		}
+		return tx.Model(session).UpdateColumns(map[string]interface{}{
+			"expires_at":   time.Now().Add(s.refreshExpire),
+			"last_seen_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, errRefreshTokenReused) {
+			_ = s.RevokeFamily(existing.FamilyID)
+		}
+		return nil, err
+	}
+	return pair, nil
+}
+
+// 作废整个令牌族，对应的会话同时撤销
+func (s *TokenService) RevokeFamily(familyID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+// 刷新令牌所属的会话，会话记录之前签发的令牌族补建一个
+func familySession(tx *gorm.DB, refreshToken *models.RefreshToken) (*models.Session, error) {
+	var session models.Session
+	err := tx.Where("family_id = ?", refreshToken.FamilyID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return createSession(tx, refreshToken.UserID, refreshToken.FamilyID, "", "", refreshToken.ExpiresAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.RevokedAt != nil {
+		return nil, utils.NewAppError(401, "Session revoked")
+	}
+	return &session, nil
+}
+
+// 校验访问令牌：签名、有效期、注销列表、用户令牌版本
+// pat_ 开头的按个人访问令牌校验
+func (s *TokenService) ValidateAccessToken(tokenString string) (*utils.Claims, error) {
+	if isPersonalAccessToken(tokenString) {
+		return validatePersonalAccessToken(s.db, tokenString)
+	}
+
+	claims, err := utils.ParseToken(tokenString, s.jwtOptions)
+	if err != nil {
+		return nil, utils.NewAppError(401, "Invalid token")
+	}
+
+	// 挑战令牌等其他类型的令牌不能访问接口
+	if claims.ID == "" || claims.TokenType != "" {
+		return nil, utils.NewAppError(401, "Invalid token")
+	}
+	if err := s.checkNotRevoked(claims); err != nil {
+		return nil, err
+	}
+	// 会话被撤销后，其访问令牌立即失效
+	if claims.SessionID != 0 {
+		if err := checkSession(s.db, claims.UserID, claims.SessionID); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// 签发短期的特定用途令牌（如两步验证挑战令牌）
+func (s *TokenService) IssueTypedToken(user *models.User, tokenType string, expire time.Duration) (string, error) {
+	return utils.GenerateTokenWithExpire(s.jwtOptions, utils.Claims{
+		UserID:       user.ID,
+		Username:     user.Username,
+		TokenVersion: user.TokenVersion,
+		TokenType:    tokenType,
+	}, expire)
+}
+
+// 校验特定用途令牌：类型必须一致，且未被使用、未被注销
+func (s *TokenService) ParseTypedToken(tokenString, tokenType string) (*utils.Claims, error) {
+	claims, err := utils.ParseToken(tokenString, s.jwtOptions)
+	if err != nil || claims.ID == "" || claims.TokenType != tokenType {
+		return nil, utils.NewAppError(401, "Invalid token")
+	}
+	if err := s.checkNotRevoked(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// 注销单个令牌（一次性令牌使用后调用）
+func (s *TokenService) RevokeToken(claims *utils.Claims) error {
+	return s.revocations.Revoke(claims.ID, claims.ExpiresAt.Time)
+}
+
+// 检查注销列表和用户令牌版本
+func (s *TokenService) checkNotRevoked(claims *utils.Claims) error {
+	revoked, err := s.revocations.IsRevoked(claims.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return utils.NewAppError(401, "Token revoked")
+	}
+
+	var user models.User
+	if err := s.db.Select("id", "token_version").First(&user, claims.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(401, "User not found")
+		}
+		return err
+	}
+	if user.TokenVersion != claims.TokenVersion {
+		return utils.NewAppError(401, "Token revoked")
+	}
+	return nil
+}
+
+// 退出登录：注销当前访问令牌并结束当前会话，可选同时作废刷新令牌所在的令牌族
+func (s *TokenService) Logout(claims *utils.Claims, refreshToken string) error {
+	if err := s.revocations.Revoke(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+	if claims.SessionID != 0 {
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			_, err := revokeSession(tx, claims.UserID, claims.SessionID)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	var existing models.RefreshToken
+	if err := s.db.Where("token_hash = ? AND user_id = ?", utils.HashToken(refreshToken), claims.UserID).First(&existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.RevokeFamily(existing.FamilyID)
+}
+
+// 退出所有设备：令牌版本 +1 使已签发的访问令牌全部失效，并作废全部刷新令牌
+func (s *TokenService) LogoutAll(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return revokeUserTokens(tx, userID)
+	})
+}
+
+// 作废用户的全部令牌和会话（需在事务中调用）
+func revokeUserTokens(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		return err
+	}
+	if err := revokeSessions(tx, userID); err != nil {
+		return err
+	}
+	return tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (s *TokenService) issueTokens(tx *gorm.DB, user *models.User, session *models.Session) (*models.TokenPair, error) {
+	permissions, err := rolePermissions(tx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := utils.GenerateToken(s.jwtOptions, utils.Claims{
+		UserID:       user.ID,
+		Username:     user.Username,
+		TokenVersion: user.TokenVersion,
+		Role:         user.Role,
+		Permissions:  permissions,
+		SessionID:    session.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  session.FamilyID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.refreshExpire),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.jwtOptions.Expire.Seconds()),
+	}, nil
+}
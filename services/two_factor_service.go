@@ -0,0 +1,387 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+var errInvalidTwoFactorCode = utils.NewAppError(401, "Invalid two-factor code")
+
+// TOTP 两步验证：绑定、解绑、恢复码，以及登录第二步的校验
+type TwoFactorService struct {
+	db              *gorm.DB
+	tokenService    *TokenService
+	loginGuard      *LoginGuard // 为 nil 时不限制验证码尝试
+	encryptionKey   []byte      // TOTP 密钥加密用，32 字节
+	issuer          string      // 验证器 App 中显示的服务名称
+	challengeExpire time.Duration
+}
+
+func NewTwoFactorService(db *gorm.DB, tokenService *TokenService, loginGuard *LoginGuard, encryptionKey []byte, issuer string, challengeExpire time.Duration) *TwoFactorService {
+	return &TwoFactorService{
+		db:              db,
+		tokenService:    tokenService,
+		loginGuard:      loginGuard,
+		encryptionKey:   encryptionKey,
+		issuer:          issuer,
+		challengeExpire: challengeExpire,
+	}
+}
+
+// 已开启两步验证的用户数
+func CountTwoFactorUsers(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Model(&models.TwoFactor{}).Where("enabled_at IS NOT NULL").Count(&count).Error
+	return count, err
+}
+
+// 查询两步验证状态
+func (s *TwoFactorService) Status(userID uint) (*models.TwoFactorStatusResponse, error) {
+	tf, err := s.find(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.TwoFactorStatusResponse{}
+	if tf == nil || tf.EnabledAt == nil {
+		return status, nil
+	}
+	status.Enabled = true
+	status.EnabledAt = tf.EnabledAt
+	if err := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&status.RecoveryCodesRemaining).Error; err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// 是否已开启两步验证
+func (s *TwoFactorService) IsEnabled(userID uint) (bool, error) {
+	tf, err := s.find(userID)
+	if err != nil {
+		return false, err
+	}
+	return tf != nil && tf.EnabledAt != nil, nil
+}
+
+// 开始绑定：生成新密钥，用户在验证器 App 中添加后调用 Enable 确认
+func (s *TwoFactorService) Setup(userID uint) (*models.TwoFactorSetupResponse, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(404, "User not found")
+		}
+		return nil, err
+	}
+
+	tf, err := s.find(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil && tf.EnabledAt != nil {
+		return nil, utils.NewAppError(409, "Two-factor authentication already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.EncryptString(s.encryptionKey, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未确认的绑定直接覆盖
+	if tf == nil {
+		tf = &models.TwoFactor{UserID: userID}
+	}
+	tf.Secret = encrypted
+	tf.LastUsedStep = 0
+	if err := s.db.Save(tf).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.issuer, user.Username, secret),
+	}, nil
+}
+
+// 确认绑定：校验验证器生成的验证码，开启两步验证并返回恢复码（只返回这一次）
+func (s *TwoFactorService) Enable(userID uint, code string) ([]string, error) {
+	tf, err := s.find(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, utils.NewAppError(400, "Two-factor setup not started")
+	}
+	if tf.EnabledAt != nil {
+		return nil, utils.NewAppError(409, "Two-factor authentication already enabled")
+	}
+
+	if err := s.verifyTOTP(tf, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(tf).Update("enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// 关闭两步验证：需要密码和验证码（或恢复码）
+func (s *TwoFactorService) Disable(userID uint, password, code string) error {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(404, "User not found")
+		}
+		return err
+	}
+	if !checkPassword(user.Password, password) {
+		return utils.NewAppError(401, "Invalid password")
+	}
+
+	tf, err := s.findEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyCode(tf, code); err != nil {
+		return err
+	}
+	return s.remove(userID)
+}
+
+// 重新生成恢复码，旧的恢复码全部失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	tf, err := s.findEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyTOTP(tf, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// 管理员：重置用户的两步验证（用户丢失验证器和恢复码时）
+func (s *TwoFactorService) Reset(userID uint) error {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(404, "User not found")
+		}
+		return err
+	}
+	return s.remove(userID)
+}
+
+// 登录第一步：签发挑战令牌
+func (s *TwoFactorService) IssueChallenge(user *models.User) (*models.TwoFactorChallenge, error) {
+	token, err := s.tokenService.IssueTypedToken(user, utils.TokenTypeTwoFactorChallenge, s.challengeExpire)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int64(s.challengeExpire.Seconds()),
+	}, nil
+}
+
+// 登录第二步：校验挑战令牌和验证码（或恢复码），成功后挑战令牌作废
+// 验证码错误与密码错误共用失败计数，同样会触发递增延迟和锁定
+func (s *TwoFactorService) CompleteLogin(challengeToken, code, clientIP string) (*models.User, error) {
+	claims, err := s.tokenService.ParseTypedToken(challengeToken, utils.TokenTypeTwoFactorChallenge)
+	if err != nil {
+		return nil, utils.NewAppError(401, "Invalid or expired challenge token")
+	}
+
+	if s.loginGuard != nil {
+		if err := s.loginGuard.Check(claims.Username, clientIP); err != nil {
+			return nil, err
+		}
+	}
+
+	tf, err := s.findEnabled(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(tf, code); err != nil {
+		if s.loginGuard != nil {
+			if lockErr := s.loginGuard.Fail(claims.Username, clientIP); lockErr != nil {
+				return nil, lockErr
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.tokenService.RevokeToken(claims); err != nil {
+		return nil, err
+	}
+	if s.loginGuard != nil {
+		if err := s.loginGuard.Succeed(claims.Username); err != nil {
+			return nil, err
+		}
+	}
+
+	var user models.User
+	if err := s.db.First(&user, claims.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(401, "User not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *TwoFactorService) find(userID uint) (*models.TwoFactor, error) {
+	var tf models.TwoFactor
+	if err := s.db.Where("user_id = ?", userID).First(&tf).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tf, nil
+}
+
+func (s *TwoFactorService) findEnabled(userID uint) (*models.TwoFactor, error) {
+	tf, err := s.find(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil || tf.EnabledAt == nil {
+		return nil, utils.NewAppError(400, "Two-factor authentication not enabled")
+	}
+	return tf, nil
+}
+
+func (s *TwoFactorService) remove(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// 6 位数字按 TOTP 验证码校验，其他按恢复码校验
+func (s *TwoFactorService) verifyCode(tf *models.TwoFactor, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == utils.TOTPDigits && strings.Trim(code, "0123456789") == "" {
+		return s.verifyTOTP(tf, code)
+	}
+	return useRecoveryCode(s.db, tf.UserID, code)
+}
+
+// 校验 TOTP 验证码，同一时间步的验证码只能使用一次
+func (s *TwoFactorService) verifyTOTP(tf *models.TwoFactor, code string) error {
+	secret, err := utils.DecryptString(s.encryptionKey, tf.Secret)
+	if err != nil {
+		return err
+	}
+	step, ok := utils.ValidateTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return errInvalidTwoFactorCode
+	}
+
+	// 条件更新：并发提交同一验证码时只有一个能成功
+	result := s.db.Model(&models.TwoFactor{}).
+		Where("id = ? AND last_used_step < ?", tf.ID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInvalidTwoFactorCode
+	}
+	tf.LastUsedStep = step
+	return nil
+}
+
+// 使用恢复码
+func useRecoveryCode(db *gorm.DB, userID uint, code string) error {
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// 替换用户的恢复码（需在事务中调用），返回明文
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// 恢复码格式：xxxxx-xxxxx（小写 base32，50 位随机）
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return s[:5] + "-" + s[5:], nil
+}
+
+// 忽略大小写、空格和连字符
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
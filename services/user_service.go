@@ -2,37 +2,63 @@ package services
 
 import (
 	"errors"
+	"log"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 
 	"gorm.io/gorm"
 
 	"gin-examples/project/models"
 	"gin-examples/project/utils"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
-	db *gorm.DB
+	db             *gorm.DB
+	loginGuard     *LoginGuard // 为 nil 时不限制登录尝试
+	passwordPolicy PasswordPolicy
+	inviteRequired bool // 邀请注册模式：注册必须提供有效的邀请码
+}
+
+func NewUserService(db *gorm.DB, loginGuard *LoginGuard, passwordPolicy PasswordPolicy, inviteRequired bool) *UserService {
+	return &UserService{db: db, loginGuard: loginGuard, passwordPolicy: passwordPolicy, inviteRequired: inviteRequired}
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+// 保留的用户名：与 /users 下的固定路由同名，注册后公开资料页 /users/:username 无法访问
+var reservedUsernames = []string{"me", "sta"}
+
+func isReservedUsername(username string) bool {
+	return slices.Contains(reservedUsernames, strings.ToLower(username))
 }
 
 func (s *UserService) CreateUser(req models.CreateUserRequest) (*models.User, error) {
-	// 检查用户名是否已存在
+	if isReservedUsername(req.Username) {
+		return nil, utils.NewAppError(400, "Username is reserved")
+	}
+
+	// 检查用户名是否已存在（已删除的用户仍占用唯一索引）
 	var existingUser models.User
-	if err := s.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+	if err := s.db.Unscoped().Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
 		return nil, utils.NewAppError(409, "Username already exists")
 	}
 
 	// 检查邮箱是否已存在
-	if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	if err := s.db.Unscoped().Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 		return nil, utils.NewAppError(409, "Email already exists")
 	}
 
+	if s.inviteRequired && req.InviteCode == "" {
+		return nil, utils.NewAppError(403, "Invite code required")
+	}
+
+	if err := s.passwordPolicy.Validate(req.Password, req.Username); err != nil {
+		return nil, err
+	}
+
 	// 加密密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(req.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -41,11 +67,29 @@ func (s *UserService) CreateUser(req models.CreateUserRequest) (*models.User, er
 	user := models.User{
 		Username:   req.Username,
 		Email:      req.Email,
-		Password:   string(hashedPassword),
+		Password:   hashedPassword,
 		PostNumber: 0,
 	}
 
-	if err := s.db.Create(&user).Error; err != nil {
+	// 提供了邀请码时（开放注册模式下也可以）核销邀请码并记录邀请人
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var invite *models.Invite
+		if req.InviteCode != "" {
+			var err error
+			if invite, err = redeemInvite(tx, req.InviteCode); err != nil {
+				return err
+			}
+			user.InvitedByID = &invite.CreatedByID
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if invite != nil {
+			return tx.Create(&models.InviteRedemption{InviteID: invite.ID, UserID: user.ID}).Error
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -63,22 +107,48 @@ func (s *UserService) GetUserByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
-func (s *UserService) Authenticate(username, password string) (*models.User, error) {
+// 用户名密码登录，按用户名和客户端 IP 限制失败次数
+func (s *UserService) Authenticate(username, password, clientIP string) (*models.User, error) {
+	if s.loginGuard != nil {
+		if err := s.loginGuard.Check(username, clientIP); err != nil {
+			return nil, err
+		}
+	}
+
 	var user models.User
 	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, utils.NewAppError(401, "Invalid credentials")
+			// 用户不存在同样计入失败次数，并校验一次固定哈希，使响应时间与密码错误相同，避免探测用户名
+			checkPassword(dummyPasswordHash(), password)
+			return nil, s.authenticateFailed(username, clientIP)
 		}
 		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, utils.NewAppError(401, "Invalid credentials")
+	if !checkPassword(user.Password, password) {
+		return nil, s.authenticateFailed(username, clientIP)
+	}
+	rehashPasswordIfNeeded(s.db, &user, password)
+
+	if s.loginGuard != nil {
+		if err := s.loginGuard.Succeed(username); err != nil {
+			return nil, err
+		}
 	}
 
 	return &user, nil
 }
 
+// 记录登录失败，本次失败触发锁定时返回锁定提示
+func (s *UserService) authenticateFailed(username, clientIP string) error {
+	if s.loginGuard != nil {
+		if err := s.loginGuard.Fail(username, clientIP); err != nil {
+			return err
+		}
+	}
+	return utils.NewAppError(401, "Invalid credentials")
+}
+
 func (s *UserService) UpdateUser(id uint, req models.UpdateUserRequest) (*models.User, error) {
 	user, err := s.GetUserByID(id)
 	if err != nil {
@@ -88,10 +158,40 @@ func (s *UserService) UpdateUser(id uint, req models.UpdateUserRequest) (*models
 	// 如果更新邮箱，检查是否已存在
 	if req.Email != "" && req.Email != user.Email {
 		var existingUser models.User
-		if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+		if err := s.db.Unscoped().Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 			return nil, utils.NewAppError(409, "Email already exists")
 		}
 		user.Email = req.Email
+		user.EmailVerifiedAt = nil // 新邮箱需要重新验证
+	}
+
+	if req.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Bio != nil {
+		user.Bio = strings.TrimSpace(*req.Bio)
+	}
+	if req.Location != nil {
+		user.Location = strings.TrimSpace(*req.Location)
+	}
+	if req.Website != nil {
+		website := strings.TrimSpace(*req.Website)
+		if website != "" && !isHTTPURL(website) {
+			return nil, utils.NewAppError(400, "Invalid website URL")
+		}
+		user.Website = website
+	}
+	if req.SocialLinks != nil {
+		links := models.SocialLinks{}
+		for name, link := range req.SocialLinks {
+			name = strings.ToLower(strings.TrimSpace(name))
+			link = strings.TrimSpace(link)
+			if !socialLinkName.MatchString(name) || len(link) > 200 || !isHTTPURL(link) {
+				return nil, utils.NewAppErrorWithDetails(400, "Invalid social link", name)
+			}
+			links[name] = link
+		}
+		user.SocialLinks = links
 	}
 
 	if err := s.db.Save(user).Error; err != nil {
@@ -101,6 +201,174 @@ func (s *UserService) UpdateUser(id uint, req models.UpdateUserRequest) (*models
 	return user, nil
 }
 
+// 修改密码：校验当前密码，成功后此前签发的令牌（含个人访问令牌）全部失效
+func (s *UserService) ChangePassword(userID uint, currentPassword, newPassword string) (*models.User, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !checkPassword(user.Password, currentPassword) {
+		return nil, utils.NewAppError(400, "Current password is incorrect")
+	}
+	if checkPassword(user.Password, newPassword) {
+		return nil, utils.NewAppError(400, "New password must be different from the current password")
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.resetPassword(tx, userID, newPassword)
+	}); err != nil {
+		return nil, err
+	}
+	return s.GetUserByID(userID)
+}
+
+// 重置密码（需在事务中调用）：校验密码策略，与注册使用相同的加密方式，并使已签发的令牌全部失效
+func (s *UserService) resetPassword(tx *gorm.DB, userID uint, newPassword string) error {
+	var user models.User
+	if err := tx.Select("id", "username").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(404, "User not found")
+		}
+		return err
+	}
+	if err := s.passwordPolicy.Validate(newPassword, user.Username); err != nil {
+		return err
+	}
+
+	hashedPassword, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("password", hashedPassword).Error; err != nil {
+		return err
+	}
+	if err := revokeAccessTokens(tx, userID); err != nil {
+		return err
+	}
+	return revokeUserTokens(tx, userID)
+}
+
+// 当前使用的密码哈希算法，由 SetPasswordHasher 按配置替换
+var passwordHasher utils.PasswordHasher = utils.NewArgon2idHasher()
+
+// 用户不存在时用于校验的固定哈希，使用当前算法和参数生成，首次使用时计算
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// 设置密码哈希算法（启动时调用）。新密码使用该算法，旧哈希仍可校验，登录时自动升级
+func SetPasswordHasher(hasher utils.PasswordHasher) {
+	passwordHasher = hasher
+	dummyHashOnce = sync.Once{}
+}
+
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		hash, err := passwordHasher.Hash("dummy-password-for-unknown-users")
+		if err != nil {
+			log.Printf("hash dummy password failed: %v", err)
+		}
+		dummyHash = hash
+	})
+	return dummyHash
+}
+
+// 密码加密
+func hashPassword(password string) (string, error) {
+	return passwordHasher.Hash(password)
+}
+
+// 校验密码
+func checkPassword(hashedPassword, password string) bool {
+	ok, err := passwordHasher.Verify(hashedPassword, password)
+	if err != nil {
+		log.Printf("verify password hash failed: %v", err)
+		return false
+	}
+	return ok
+}
+
+// 哈希算法或参数已过时，用明文密码重新哈希（只在校验通过后调用）
+func rehashPasswordIfNeeded(db *gorm.DB, user *models.User, password string) {
+	if !passwordHasher.NeedsRehash(user.Password) {
+		return
+	}
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		log.Printf("rehash password for user %d failed: %v", user.ID, err)
+		return
+	}
+	// 条件更新：期间密码已被修改时不覆盖
+	if err := db.Model(&models.User{}).
+		Where("id = ? AND password = ?", user.ID, user.Password).
+		UpdateColumn("password", hashedPassword).Error; err != nil {
+		log.Printf("rehash password for user %d failed: %v", user.ID, err)
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// 公开资料：按用户名查询，附带已发布且审核通过的文章
+func (s *UserService) GetPublicProfile(username string, pageNo, pageSize int) (*models.PublicProfileResponse, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(404, "User not found")
+		}
+		return nil, err
+	}
+
+	profile := &models.PublicProfileResponse{
+		Username:      user.Username,
+		PostNumber:    user.PostNumber,
+		CreatedAt:     user.CreatedAt,
+		ProfileFields: NewProfileFields(&user),
+	}
+	// Session 之后可以复用同一组条件查询总数和分页
+	published := s.db.Model(&models.Post{}).Scopes(publishedPosts).Where("user_id = ? AND audit_status = ?", user.ID, "active").Session(&gorm.Session{})
+	if err := published.Count(&profile.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := published.Scopes(utils.Sql.Paginate(pageNo, pageSize), utils.Sql.OrderCreateAt(), withTaxonomy).
+		Find(&profile.Posts).Error; err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// 用户的公开资料字段
+func NewProfileFields(user *models.User) models.ProfileFields {
+	return models.ProfileFields{
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Website:     user.Website,
+		Location:    user.Location,
+		SocialLinks: user.SocialLinks,
+		Avatar:      AvatarURLs(user.Avatar),
+	}
+}
+
+// 社交账号名称：小写字母、数字、下划线、连字符
+var socialLinkName = regexp.MustCompile(`^[a-z0-9_-]{1,20}$`)
+
+// 只接受 http、https 的绝对地址
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// 分页查询全部用户
+func (s *UserService) ListUsers(pageNo, pageSize int) ([]models.User, error) {
+	var users []models.User
+	if err := s.db.Scopes(utils.Sql.Paginate(pageNo, pageSize)).
+		Order("id").
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // 查询每个用户审计合法、非法的文章数
 func (s *UserService) StatisticPostAuditStatus() ([]models.StatisticUserResponse, error) {
 	var sta []models.StatisticUserResponse
@@ -0,0 +1,467 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+)
+
+// 认证器数据标志位
+const (
+	authenticatorFlagUserPresent  = 0x01
+	authenticatorFlagUserVerified = 0x04
+	authenticatorFlagAttested     = 0x40
+)
+
+// 登录失败统一返回，不区分凭据不存在、签名错误等原因
+var errPasskeyLoginFailed = utils.NewAppError(401, "Passkey authentication failed")
+
+// WebAuthn 依赖方参数（来自配置 auth.webauthn.*）
+type WebAuthnOptions struct {
+	RPID            string   // 依赖方 ID，通常为站点域名
+	RPName          string   // 认证器中显示的名称
+	Origins         []string // 允许发起仪式的页面来源
+	ChallengeExpire time.Duration
+}
+
+// 通行密钥：注册、登录、管理
+// 只接受要求用户验证（指纹、PIN 等）的认证器，不校验证明（attestation: none）
+type WebAuthnService struct {
+	db      *gorm.DB
+	options WebAuthnOptions
+}
+
+func NewWebAuthnService(db *gorm.DB, options WebAuthnOptions) *WebAuthnService {
+	return &WebAuthnService{db: db, options: options}
+}
+
+// 注册第一步：生成创建凭据的选项
+func (s *WebAuthnService) BeginRegistration(userID uint) (*models.PasskeyOptionsResponse, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(404, "User not found")
+		}
+		return nil, err
+	}
+
+	credentials, err := s.ListPasskeys(userID)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.newChallenge(userID, models.WebAuthnChallengeRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PasskeyOptionsResponse{
+		SessionID: challenge.ID,
+		PublicKey: models.PublicKeyCredentialCreationOptions{
+			RP: models.RelyingParty{ID: s.options.RPID, Name: s.options.RPName},
+			User: models.PublicKeyCredentialUser{
+				ID:          webAuthnUserHandle(user.ID),
+				Name:        user.Username,
+				DisplayName: user.Username,
+			},
+			Challenge: challenge.Challenge,
+			PubKeyCredParams: []models.PublicKeyCredentialParameters{
+				{Type: "public-key", Alg: utils.COSEAlgES256},
+				{Type: "public-key", Alg: utils.COSEAlgEdDSA},
+				{Type: "public-key", Alg: utils.COSEAlgRS256},
+			},
+			Timeout:            s.options.ChallengeExpire.Milliseconds(),
+			ExcludeCredentials: credentialDescriptors(credentials),
+			AuthenticatorSelection: models.AuthenticatorSelection{
+				ResidentKey:        "required",
+				RequireResidentKey: true,
+				UserVerification:   "required",
+			},
+			Attestation: "none",
+		},
+	}, nil
+}
+
+// 注册第二步：校验认证器返回的凭据并保存公钥
+func (s *WebAuthnService) FinishRegistration(userID uint, req models.FinishPasskeyRegistrationRequest) (*models.WebAuthnCredential, error) {
+	challenge, err := s.consumeChallenge(req.SessionID, models.WebAuthnChallengeRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID != userID {
+		return nil, utils.NewAppError(400, "Invalid or expired challenge")
+	}
+	if req.Credential.Type != "public-key" {
+		return nil, utils.NewAppError(400, "Invalid credential type")
+	}
+
+	clientDataJSON, err := decodeBase64URL(req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, utils.NewAppError(400, "Invalid client data")
+	}
+	if err := s.verifyClientData(clientDataJSON, "webauthn.create", challenge.Challenge); err != nil {
+		return nil, err
+	}
+
+	// 证明对象：{fmt, attStmt, authData}，只使用其中的认证器数据
+	attestationObject, err := decodeBase64URL(req.Credential.Response.AttestationObject)
+	if err != nil {
+		return nil, utils.NewAppError(400, "Invalid attestation object")
+	}
+	value, _, err := utils.DecodeCBOR(attestationObject)
+	if err != nil {
+		return nil, utils.NewAppError(400, "Invalid attestation object")
+	}
+	attestation, _ := value.(map[interface{}]interface{})
+	rawAuthData, _ := attestation["authData"].([]byte)
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, utils.NewAppError(400, "Invalid authenticator data")
+	}
+	if err := s.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if authData.flags&authenticatorFlagAttested == 0 {
+		return nil, utils.NewAppError(400, "Missing attested credential data")
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(authData.credentialID)
+	if credentialID != strings.TrimRight(req.Credential.ID, "=") {
+		return nil, utils.NewAppError(400, "Credential id mismatch")
+	}
+	if _, err := utils.ParseCOSEKey(authData.publicKey); err != nil {
+		return nil, utils.NewAppError(400, "Unsupported credential public key")
+	}
+
+	var exists int64
+	if err := s.db.Model(&models.WebAuthnCredential{}).Where("credential_id = ?", credentialID).Count(&exists).Error; err != nil {
+		return nil, err
+	}
+	if exists > 0 {
+		return nil, utils.NewAppError(409, "Passkey already registered")
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "Passkey"
+	}
+	credential := models.WebAuthnCredential{
+		UserID:       userID,
+		Name:         name,
+		CredentialID: credentialID,
+		PublicKey:    authData.publicKey,
+		SignCount:    authData.signCount,
+		AAGUID:       formatAAGUID(authData.aaguid),
+	}
+	if err := s.db.Create(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// 登录第一步：生成获取断言的选项。指定了用户名时只允许该用户的凭据，否则由认证器选择可发现凭据
+// 用户名不存在时按未指定处理，避免泄露哪些用户名已注册
+func (s *WebAuthnService) BeginLogin(username string) (*models.PasskeyOptionsResponse, error) {
+	var userID uint
+	var credentials []models.WebAuthnCredential
+	if username != "" {
+		var user models.User
+		err := s.db.Select("id").Where("username = ?", username).First(&user).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			userID = user.ID
+			if credentials, err = s.ListPasskeys(user.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	challenge, err := s.newChallenge(userID, models.WebAuthnChallengeLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PasskeyOptionsResponse{
+		SessionID: challenge.ID,
+		PublicKey: models.PublicKeyCredentialRequestOptions{
+			Challenge:        challenge.Challenge,
+			Timeout:          s.options.ChallengeExpire.Milliseconds(),
+			RPID:             s.options.RPID,
+			AllowCredentials: credentialDescriptors(credentials),
+			UserVerification: "required",
+		},
+	}, nil
+}
+
+// 登录第二步：校验断言签名，返回凭据所属的用户
+func (s *WebAuthnService) FinishLogin(req models.FinishPasskeyLoginRequest) (*models.User, error) {
+	challenge, err := s.consumeChallenge(req.SessionID, models.WebAuthnChallengeLogin)
+	if err != nil {
+		return nil, err
+	}
+	if req.Credential.Type != "public-key" {
+		return nil, errPasskeyLoginFailed
+	}
+
+	var credential models.WebAuthnCredential
+	if err := s.db.Where("credential_id = ?", strings.TrimRight(req.Credential.ID, "=")).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errPasskeyLoginFailed
+		}
+		return nil, err
+	}
+	if challenge.UserID != 0 && challenge.UserID != credential.UserID {
+		return nil, errPasskeyLoginFailed
+	}
+	if req.Credential.Response.UserHandle != "" &&
+		strings.TrimRight(req.Credential.Response.UserHandle, "=") != webAuthnUserHandle(credential.UserID) {
+		return nil, errPasskeyLoginFailed
+	}
+
+	clientDataJSON, err := decodeBase64URL(req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, errPasskeyLoginFailed
+	}
+	if err := s.verifyClientData(clientDataJSON, "webauthn.get", challenge.Challenge); err != nil {
+		return nil, err
+	}
+	rawAuthData, err := decodeBase64URL(req.Credential.Response.AuthenticatorData)
+	if err != nil {
+		return nil, errPasskeyLoginFailed
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, errPasskeyLoginFailed
+	}
+	if err := s.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	// 签名内容：authenticatorData || SHA-256(clientDataJSON)
+	signature, err := decodeBase64URL(req.Credential.Response.Signature)
+	if err != nil {
+		return nil, errPasskeyLoginFailed
+	}
+	publicKey, err := utils.ParseCOSEKey(credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if !publicKey.Verify(append(slices.Clip(rawAuthData), clientDataHash[:]...), signature) {
+		return nil, errPasskeyLoginFailed
+	}
+
+	// 计数器不增反减说明认证器可能被克隆；不支持计数器的认证器始终为 0
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return nil, utils.NewAppError(401, "Passkey sign counter check failed")
+	}
+	// 条件更新：并发使用同一断言时只有一个请求成功
+	result := s.db.Model(&models.WebAuthnCredential{}).
+		Where("id = ? AND sign_count = ?", credential.ID, credential.SignCount).
+		Updates(map[string]interface{}{"sign_count": authData.signCount, "last_used_at": time.Now()})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errPasskeyLoginFailed
+	}
+
+	var user models.User
+	if err := s.db.First(&user, credential.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errPasskeyLoginFailed
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// 查询用户的通行密钥
+func (s *WebAuthnService) ListPasskeys(userID uint) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	if err := s.db.Where("user_id = ?", userID).Order("id DESC").Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// 删除通行密钥
+func (s *WebAuthnService) DeletePasskey(userID, id uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.WebAuthnCredential{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewAppError(404, "Passkey not found")
+	}
+	return nil
+}
+
+// 生成挑战，顺带清理过期的挑战
+func (s *WebAuthnService) newChallenge(userID uint, purpose string) (*models.WebAuthnChallenge, error) {
+	if err := s.db.Where("expires_at < ?", time.Now()).Delete(&models.WebAuthnChallenge{}).Error; err != nil {
+		return nil, err
+	}
+
+	id, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	value, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	challenge := models.WebAuthnChallenge{
+		ID:        id,
+		UserID:    userID,
+		Purpose:   purpose,
+		Challenge: value,
+		ExpiresAt: time.Now().Add(s.options.ChallengeExpire),
+	}
+	if err := s.db.Create(&challenge).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// 取出并删除挑战，每个挑战只能使用一次
+func (s *WebAuthnService) consumeChallenge(id, purpose string) (*models.WebAuthnChallenge, error) {
+	var challenge models.WebAuthnChallenge
+	if err := s.db.Where("id = ?", id).First(&challenge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(400, "Invalid or expired challenge")
+		}
+		return nil, err
+	}
+	result := s.db.Where("id = ?", id).Delete(&models.WebAuthnChallenge{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || challenge.Purpose != purpose || time.Now().After(challenge.ExpiresAt) {
+		return nil, utils.NewAppError(400, "Invalid or expired challenge")
+	}
+	return &challenge, nil
+}
+
+// 校验客户端数据：仪式类型、挑战、页面来源
+func (s *WebAuthnService) verifyClientData(raw []byte, ceremony, challenge string) error {
+	var clientData struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Origin    string `json:"origin"`
+	}
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return utils.NewAppError(400, "Invalid client data")
+	}
+	if clientData.Type != ceremony {
+		return utils.NewAppError(400, "Invalid client data type")
+	}
+	if strings.TrimRight(clientData.Challenge, "=") != challenge {
+		return utils.NewAppError(400, "Challenge mismatch")
+	}
+	if !slices.Contains(s.options.Origins, clientData.Origin) {
+		return utils.NewAppError(400, "Origin not allowed: "+clientData.Origin)
+	}
+	return nil
+}
+
+// 校验认证器数据：依赖方 ID 摘要、用户在场、用户已验证
+func (s *WebAuthnService) verifyAuthenticatorData(authData *authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(s.options.RPID))
+	if !bytes.Equal(authData.rpIDHash, rpIDHash[:]) {
+		return utils.NewAppError(400, "Relying party id mismatch")
+	}
+	if authData.flags&authenticatorFlagUserPresent == 0 {
+		return utils.NewAppError(400, "User presence required")
+	}
+	if authData.flags&authenticatorFlagUserVerified == 0 {
+		return utils.NewAppError(400, "User verification required")
+	}
+	return nil
+}
+
+// 认证器数据（WebAuthn §6.1）
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte // 以下字段只在注册时（AT 标志位）存在
+	credentialID []byte
+	publicKey    []byte // COSE 编码
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data too short")
+	}
+	authData := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if authData.flags&authenticatorFlagAttested == 0 {
+		return authData, nil
+	}
+
+	// 凭据数据：aaguid(16) || 凭据 ID 长度(2) || 凭据 ID || COSE 公钥
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, errors.New("attested credential data too short")
+	}
+	authData.aaguid = rest[:16]
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLength == 0 || idLength > 1023 || len(rest) < idLength {
+		return nil, errors.New("invalid credential id length")
+	}
+	authData.credentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	// 公钥之后可能还有扩展数据，按 CBOR 解码出公钥的长度
+	_, remaining, err := utils.DecodeCBOR(rest)
+	if err != nil {
+		return nil, err
+	}
+	authData.publicKey = append([]byte(nil), rest[:len(rest)-len(remaining)]...)
+	return authData, nil
+}
+
+// 用户句柄：用户 ID 的 8 字节大端编码，不含用户名、邮箱等个人信息
+func webAuthnUserHandle(userID uint) string {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return base64.RawURLEncoding.EncodeToString(handle)
+}
+
+func credentialDescriptors(credentials []models.WebAuthnCredential) []models.PublicKeyCredentialDescriptor {
+	descriptors := make([]models.PublicKeyCredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptors = append(descriptors, models.PublicKeyCredentialDescriptor{Type: "public-key", ID: credential.CredentialID})
+	}
+	return descriptors
+}
+
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", aaguid[0:4], aaguid[4:6], aaguid[6:8], aaguid[8:10], aaguid[10:16])
+}
+
+// 客户端的 base64url 可能带填充
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
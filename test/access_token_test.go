@@ -0,0 +1,79 @@
+package test
+
+import (
+	"bytes"
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessToken_ScopesAndRevocation(t *testing.T) {
+	cfg := config.Load()
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	assert.NoError(t, services.NewRoleService(db).SeedDefaultRoles())
+	router := setupTestHandlerRouter(cfg, db)
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+	post, err := services.NewPostService(db).CreatePost(user.ID, models.CreatePostRequest{Title: "Hello", Content: "World"})
+	assert.NoError(t, err)
+
+	accessTokenService := services.NewAccessTokenService(db)
+	created, err := accessTokenService.CreateAccessToken(user.ID, models.CreateAccessTokenRequest{
+		Name:   "ci",
+		Scopes: []string{models.ScopePostsRead},
+	})
+	assert.NoError(t, err)
+
+	request := func(method, path, body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+created.Token)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	commentsPath := "/api/v1/comments/" + strconv.FormatUint(uint64(post.ID), 10)
+
+	// 声明了的 scope 可以访问
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/posts/me", ""))
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/posts", ""))
+
+	// 未声明的 scope、只接受登录令牌的接口：拒绝
+	assert.Equal(t, http.StatusForbidden, request("POST", "/api/v1/posts/me", `{"title": "Hi", "content": "PAT"}`))
+	assert.Equal(t, http.StatusForbidden, request("POST", "/api/v1/comments", `{"post_id": 1, "content": "PAT"}`))
+	assert.Equal(t, http.StatusForbidden, request("GET", "/api/v1/users/me/tokens", ""))
+
+	// 公开的查询接口：缺少 scope 时按匿名处理，与不带令牌相同
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", commentsPath, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, request("GET", commentsPath, ""))
+
+	// 文章未发布时：缺少 comments:read 看不到评论，有 comments:read 的作者令牌可以
+	db.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("status", models.PostStatusDraft)
+	assert.Equal(t, http.StatusNotFound, request("GET", commentsPath, ""))
+	commentsReader, err := accessTokenService.CreateAccessToken(user.ID, models.CreateAccessTokenRequest{
+		Name:   "comments",
+		Scopes: []string{models.ScopeCommentsRead},
+	})
+	assert.NoError(t, err)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", commentsPath, nil)
+	req.Header.Set("Authorization", "Bearer "+commentsReader.Token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 撤销后立即失效
+	assert.NoError(t, accessTokenService.RevokeAccessToken(user.ID, created.ID))
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/api/v1/posts/me", ""))
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/api/v1/posts", ""))
+}
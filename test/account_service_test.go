@@ -0,0 +1,176 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// 注销测试数据：alice 发表一篇文章，bob 在上面评论；alice 在 bob 的文章下评论两条
+type accountTestData struct {
+	alice, bob         *models.User
+	alicePost, bobPost *models.Post
+	accountService     *services.AccountService
+	aliceSessionID     uint
+}
+
+func setupAccountTestData(t *testing.T, db *gorm.DB, deletionPolicy string) *accountTestData {
+	t.Helper()
+	userService := services.NewUserService(db, nil, services.PasswordPolicy{}, false)
+	createUser := func(username string) *models.User {
+		user, err := userService.CreateUser(models.CreateUserRequest{Username: username, Email: username + "@example.com", Password: username + "123"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Model(user).Update("email_verified_at", time.Now())
+		return user
+	}
+	data := &accountTestData{alice: createUser("alice"), bob: createUser("bob")}
+
+	postService := services.NewPostService(db)
+	commentService := services.NewCommentService(db)
+	var err error
+	data.alicePost, err = postService.CreatePost(data.alice.ID, models.CreatePostRequest{Title: "Alice", Content: "Alice post"})
+	assert.NoError(t, err)
+	data.bobPost, err = postService.CreatePost(data.bob.ID, models.CreatePostRequest{Title: "Bob", Content: "Bob post"})
+	assert.NoError(t, err)
+	for _, comment := range []struct {
+		userID, postID uint
+	}{{data.bob.ID, data.alicePost.ID}, {data.alice.ID, data.bobPost.ID}, {data.alice.ID, data.bobPost.ID}, {data.bob.ID, data.bobPost.ID}} {
+		_, err := commentService.CreateComment(comment.userID, models.CreateCommentRequest{PostID: comment.postID, Content: "Nice post"})
+		assert.NoError(t, err)
+	}
+
+	jwtOptions := &utils.JWTOptions{Secret: []byte("test-secret"), Expire: time.Minute}
+	tokenService := services.NewTokenService(db, jwtOptions, time.Hour, services.NewMemoryRevocationStore())
+	pair, err := tokenService.IssueTokens(data.alice, "Go-http-client/1.1", "127.0.0.1")
+	assert.NoError(t, err)
+	claims, err := tokenService.ValidateAccessToken(pair.AccessToken)
+	assert.NoError(t, err)
+	data.aliceSessionID = claims.SessionID
+
+	twoFactorService := services.NewTwoFactorService(db, tokenService, nil, []byte("0123456789abcdef0123456789abcdef"), "Blog", time.Minute)
+	data.accountService = services.NewAccountService(db, twoFactorService, deletionPolicy)
+	return data
+}
+
+func TestAccountService_Export(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+	data := setupAccountTestData(t, db, models.AccountDeletionAnonymize)
+
+	var buf bytes.Buffer
+	assert.NoError(t, data.accountService.Export(data.alice, &buf))
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		files[f.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+	assert.Len(t, files, 4)
+
+	var profile models.AccountExportProfile
+	assert.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, "alice", profile.Username)
+	assert.Equal(t, "alice@example.com", profile.Email)
+
+	// 只包含自己的文章和评论
+	var posts []models.AccountExportPost
+	assert.NoError(t, json.Unmarshal(files["posts.json"], &posts))
+	if assert.Len(t, posts, 1) {
+		assert.Equal(t, "Alice", posts[0].Title)
+	}
+	var comments []models.AccountExportComment
+	assert.NoError(t, json.Unmarshal(files["comments.json"], &comments))
+	assert.Len(t, comments, 2)
+	for _, comment := range comments {
+		assert.Equal(t, data.bobPost.ID, comment.PostID)
+	}
+
+	var audit models.AccountExportAudit
+	assert.NoError(t, json.Unmarshal(files["audit.json"], &audit))
+	assert.Len(t, audit.Sessions, 1)
+	assert.NotContains(t, string(files["audit.json"]), "token_hash")
+}
+
+func TestAccountService_DeleteAnonymize(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+	data := setupAccountTestData(t, db, models.AccountDeletionAnonymize)
+
+	// 密码错误，或没有密码且不是刚登录的会话：拒绝
+	assertAppErrorCode(t, data.accountService.DeleteAccount(data.alice.ID, data.aliceSessionID, models.DeleteAccountRequest{Password: "wrong"}), 401)
+	assertAppErrorCode(t, data.accountService.DeleteAccount(data.alice.ID, 0, models.DeleteAccountRequest{}), 401)
+	db.Model(&models.Session{}).Where("id = ?", data.aliceSessionID).Update("created_at", time.Now().Add(-time.Hour))
+	assertAppErrorCode(t, data.accountService.DeleteAccount(data.alice.ID, data.aliceSessionID, models.DeleteAccountRequest{}), 401)
+
+	// 注销前已有用户注册了形如 deleted-<ID> 的用户名、邮箱：不影响注销
+	userService := services.NewUserService(db, nil, services.PasswordPolicy{}, false)
+	aliceID := strconv.FormatUint(uint64(data.alice.ID), 10)
+	_, err := userService.CreateUser(models.CreateUserRequest{Username: "deleted-" + aliceID, Email: "deleted-" + aliceID + "@deleted.invalid", Password: "squatter123"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, data.accountService.DeleteAccount(data.alice.ID, 0, models.DeleteAccountRequest{Password: "alice123"}))
+
+	// 用户匿名化并标记删除，文章和评论保留
+	var user models.User
+	assert.NoError(t, db.Unscoped().First(&user, data.alice.ID).Error)
+	assert.True(t, user.DeletedAt.Valid)
+	assert.Equal(t, fmt.Sprintf("deleted-user-%08d", data.alice.ID), user.Username)
+	assert.Greater(t, len(user.Username), 20) // 超过注册用户名的长度上限
+	assert.Empty(t, user.Password)
+
+	// 注销后：占位用户名、邮箱不能注册（409 而不是唯一索引冲突），原用户名、邮箱可以重新注册
+	_, err = userService.CreateUser(models.CreateUserRequest{Username: user.Username, Email: "placeholder@example.com", Password: "squatter123"})
+	assertAppErrorCode(t, err, 409)
+	_, err = userService.CreateUser(models.CreateUserRequest{Username: "placeholder", Email: user.Email, Password: "squatter123"})
+	assertAppErrorCode(t, err, 409)
+	_, err = userService.CreateUser(models.CreateUserRequest{Username: data.alice.Username, Email: data.alice.Email, Password: "alice123"})
+	assert.NoError(t, err)
+	var posts, comments, sessions int64
+	db.Model(&models.Post{}).Where("user_id = ?", data.alice.ID).Count(&posts)
+	db.Model(&models.Comment{}).Where("user_id = ?", data.alice.ID).Count(&comments)
+	db.Model(&models.Session{}).Where("user_id = ?", data.alice.ID).Count(&sessions)
+	assert.Equal(t, int64(1), posts)
+	assert.Equal(t, int64(2), comments)
+	assert.Equal(t, int64(0), sessions)
+}
+
+func TestAccountService_DeleteContent(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+	data := setupAccountTestData(t, db, models.AccountDeletionDelete)
+
+	// 没有密码的用户：刚登录的会话可以直接注销
+	assert.NoError(t, data.accountService.DeleteAccount(data.alice.ID, data.aliceSessionID, models.DeleteAccountRequest{}))
+
+	// 用户、用户的文章（连同 bob 在上面的评论）和用户的评论全部删除
+	var users, alicePosts, comments int64
+	db.Unscoped().Model(&models.User{}).Where("id = ?", data.alice.ID).Count(&users)
+	db.Unscoped().Model(&models.Post{}).Where("id = ?", data.alicePost.ID).Count(&alicePosts)
+	db.Unscoped().Model(&models.Comment{}).Where("user_id = ? OR post_id = ?", data.alice.ID, data.alicePost.ID).Count(&comments)
+	assert.Equal(t, int64(0), users)
+	assert.Equal(t, int64(0), alicePosts)
+	assert.Equal(t, int64(0), comments)
+
+	// bob 的文章评论数重新统计：3 条中删除了 alice 的 2 条
+	var bobPost models.Post
+	assert.NoError(t, db.First(&bobPost, data.bobPost.ID).Error)
+	assert.Equal(t, 1, int(bobPost.CommentNumber))
+	assert.Equal(t, "热评中", bobPost.CommentStatus)
+}
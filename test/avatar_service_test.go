@@ -0,0 +1,65 @@
+package test
+
+import (
+	"bytes"
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestAvatarService_UploadValidation(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	services.SetFileStorage(&utils.LocalStorage{Dir: dir, BaseURL: "/uploads"})
+	defer services.SetFileStorage(&utils.LocalStorage{Dir: "uploads", BaseURL: "/uploads"})
+
+	avatarService := services.NewAvatarService(db, 64<<10)
+
+	// 超过大小上限
+	_, err = avatarService.UploadAvatar(user.ID, make([]byte, 64<<10+1))
+	assertAppErrorCode(t, err, 413)
+
+	// 按文件内容判断类型：文本、SVG 以及伪造 PNG 文件头的数据都被拒绝
+	_, err = avatarService.UploadAvatar(user.ID, []byte("not an image"))
+	assertAppErrorCode(t, err, 415)
+	_, err = avatarService.UploadAvatar(user.ID, []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`))
+	assertAppErrorCode(t, err, 415)
+	_, err = avatarService.UploadAvatar(user.ID, append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...))
+	assertAppErrorCode(t, err, 415)
+
+	// 宽高超过上限时不解码
+	_, err = avatarService.UploadAvatar(user.ID, encodeTestPNG(t, 5000, 1))
+	assertAppErrorCode(t, err, 400)
+
+	// 被拒绝的上传不会写入任何文件
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+
+	// 合法图片生成全部尺寸
+	updated, err := avatarService.UploadAvatar(user.ID, encodeTestPNG(t, 300, 200))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, updated.Avatar)
+	for _, size := range []int{models.AvatarSizeSmall, models.AvatarSizeMedium, models.AvatarSizeLarge} {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(models.AvatarVariant(updated.Avatar, size))))
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,44 @@
+package test
+
+import (
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentService_ListCommentOfUnpublishedPost(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	author, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+	post, err := services.NewPostService(db).CreatePost(author.ID, models.CreatePostRequest{Title: "hello", Content: "hello world"})
+	assert.NoError(t, err)
+	commentService := services.NewCommentService(db)
+	_, err = commentService.CreateComment(author.ID, models.CreateCommentRequest{PostID: post.ID, Content: "first"})
+	assert.NoError(t, err)
+
+	comments, err := commentService.ListCommentByPostId(post.ID, 0, 1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+
+	// 文章改为草稿、定时或归档后，评论只有作者可以查看
+	const otherUserID = 9999
+	for _, status := range []string{models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusArchived} {
+		db.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("status", status)
+		_, err = commentService.ListCommentByPostId(post.ID, 0, 1, 10)
+		assertAppErrorCode(t, err, 404)
+		_, err = commentService.ListCommentByPostId(post.ID, otherUserID, 1, 10)
+		assertAppErrorCode(t, err, 404)
+		comments, err = commentService.ListCommentByPostId(post.ID, author.ID, 1, 10)
+		assert.NoError(t, err)
+		assert.Len(t, comments, 1)
+	}
+
+	// 文章不存在
+	_, err = commentService.ListCommentByPostId(post.ID+1, author.ID, 1, 10)
+	assertAppErrorCode(t, err, 404)
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"gin-examples/project/utils"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLines(t *testing.T) {
+	lines := utils.DiffLines("a\nb\nc\nd\n", "a\nc\nx\nd\n")
+	assert.Equal(t, []utils.DiffLine{
+		{Op: utils.DiffEqual, OldLine: 1, NewLine: 1, Text: "a"},
+		{Op: utils.DiffDelete, OldLine: 2, Text: "b"},
+		{Op: utils.DiffEqual, OldLine: 3, NewLine: 2, Text: "c"},
+		{Op: utils.DiffInsert, NewLine: 3, Text: "x"},
+		{Op: utils.DiffEqual, OldLine: 4, NewLine: 4, Text: "d"},
+	}, lines)
+
+	// 差异超过 DiffMaxEdits 时整体替换，相同的首尾行仍然保留
+	var oldLines, newLines []string
+	for i := 0; i < utils.DiffMaxEdits; i++ {
+		oldLines = append(oldLines, "old "+strconv.Itoa(i))
+		newLines = append(newLines, "new "+strconv.Itoa(i))
+	}
+	lines = utils.DiffLines("head\n"+strings.Join(oldLines, "\n")+"\ntail", "head\n"+strings.Join(newLines, "\n")+"\ntail")
+	total := 2*utils.DiffMaxEdits + 2
+	assert.Len(t, lines, total)
+	assert.Equal(t, utils.DiffLine{Op: utils.DiffEqual, OldLine: 1, NewLine: 1, Text: "head"}, lines[0])
+	assert.Equal(t, utils.DiffLine{Op: utils.DiffDelete, OldLine: 2, Text: "old 0"}, lines[1])
+	assert.Equal(t, utils.DiffLine{Op: utils.DiffInsert, NewLine: 2, Text: "new 0"}, lines[utils.DiffMaxEdits+1])
+	last := utils.DiffMaxEdits + 2
+	assert.Equal(t, utils.DiffLine{Op: utils.DiffEqual, OldLine: last, NewLine: last, Text: "tail"}, lines[total-1])
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInviteService_QuotaAndReuse(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	inviter, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+	inviteService := services.NewInviteService(db, 3, 24*time.Hour)
+	userService := services.NewUserService(db, nil, services.PasswordPolicy{}, true)
+
+	// 配额按 MaxUses 之和计算
+	invite, err := inviteService.CreateInvite(inviter.ID, false, models.CreateInviteRequest{MaxUses: 2})
+	assert.NoError(t, err)
+	_, err = inviteService.CreateInvite(inviter.ID, false, models.CreateInviteRequest{MaxUses: 2})
+	assertAppErrorCode(t, err, 403)
+	list, err := inviteService.ListInvites(inviter.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, list.Remaining)
+
+	// 邀请注册模式：必须提供邀请码，同一邀请码最多使用 MaxUses 次
+	register := func(username, code string) error {
+		_, err := userService.CreateUser(models.CreateUserRequest{
+			Username:   username,
+			Email:      username + "@example.com",
+			Password:   "invitee123",
+			InviteCode: code,
+		})
+		return err
+	}
+	assert.Error(t, register("nocode", ""))
+	assert.NoError(t, register("invitee1", invite.Code))
+	assert.NoError(t, register("invitee2", invite.Code))
+	assertAppErrorCode(t, register("invitee3", invite.Code), 400)
+	assertAppErrorCode(t, register("invitee4", "unknown-code"), 400)
+
+	var redemptions int64
+	db.Model(&models.InviteRedemption{}).Where("invite_id = ?", invite.ID).Count(&redemptions)
+	assert.Equal(t, int64(2), redemptions)
+
+	// 撤销未使用的邀请码，名额退回配额
+	unused, err := inviteService.CreateInvite(inviter.ID, false, models.CreateInviteRequest{MaxUses: 1})
+	assert.NoError(t, err)
+	list, err = inviteService.ListInvites(inviter.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, list.Remaining)
+	assert.NoError(t, inviteService.RevokeInvite(inviter.ID, unused.ID, false))
+	assertAppErrorCode(t, register("invitee5", unused.Code), 400)
+	list, err = inviteService.ListInvites(inviter.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, list.Remaining)
+
+	// 管理员不受配额限制
+	_, err = inviteService.CreateInvite(inviter.ID, true, models.CreateInviteRequest{MaxUses: 10})
+	assert.NoError(t, err)
+
+	// 管理后台分页查询
+	all, err := inviteService.ListAllInvites(1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyService_AlgorithmSwitchAndEncryption(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	encryptionKey := []byte("0123456789abcdef0123456789abcdef")
+	claims := utils.Claims{UserID: 1, Username: "admin"}
+
+	// RS256：私钥加密保存
+	rsOptions := &utils.JWTOptions{Keys: utils.NewKeySet(utils.AlgRS256), Expire: time.Minute}
+	assert.NoError(t, services.NewKeyService(db, rsOptions.Keys, encryptionKey, 720*time.Hour, 15*time.Minute, time.Hour).Refresh())
+	var rsKey models.SigningKey
+	assert.NoError(t, db.Where("algorithm = ?", utils.AlgRS256).First(&rsKey).Error)
+	assert.True(t, strings.HasPrefix(rsKey.PrivateKey, "v1:"))
+	rsToken, err := utils.GenerateToken(rsOptions, claims)
+	assert.NoError(t, err)
+
+	// 切换为 EdDSA：新令牌使用 EdDSA 签名，RS256 密钥保留，旧令牌仍可验签
+	edOptions := &utils.JWTOptions{Keys: utils.NewKeySet(utils.AlgEdDSA), Expire: time.Minute}
+	keyService := services.NewKeyService(db, edOptions.Keys, encryptionKey, 720*time.Hour, 15*time.Minute, time.Hour)
+	assert.NoError(t, keyService.Refresh())
+	assert.Len(t, edOptions.Keys.JWKS().Keys, 2)
+	assert.Equal(t, utils.AlgEdDSA, edOptions.Keys.Current().Algorithm)
+	_, err = utils.ParseToken(rsToken, edOptions)
+	assert.NoError(t, err)
+	edToken, err := utils.GenerateToken(edOptions, claims)
+	assert.NoError(t, err)
+	_, err = utils.ParseToken(edToken, edOptions)
+	assert.NoError(t, err)
+
+	// EdDSA 密钥生效超过 retain 后删除 RS256 密钥
+	db.Model(&models.SigningKey{}).Where("algorithm = ?", utils.AlgRS256).Update("activates_at", time.Now().Add(-3*time.Hour))
+	db.Model(&models.SigningKey{}).Where("algorithm = ?", utils.AlgEdDSA).Update("activates_at", time.Now().Add(-2*time.Hour))
+	assert.NoError(t, keyService.Refresh())
+	assert.Len(t, edOptions.Keys.JWKS().Keys, 1)
+	_, err = utils.ParseToken(rsToken, edOptions)
+	assert.Error(t, err)
+
+}
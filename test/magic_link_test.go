@@ -0,0 +1,95 @@
+package test
+
+import (
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMagicLinkService_RoundTrip(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	userService := services.NewUserService(db, nil, services.PasswordPolicy{}, false)
+	user, err := userService.CreateUser(models.CreateUserRequest{
+		Username: "magic",
+		Email:    "magic@example.com",
+		Password: "magic123",
+	})
+	assert.NoError(t, err)
+
+	// 邮件写入临时目录
+	mailDir := t.TempDir()
+	magicLinkService := services.NewMagicLinkService(db, userService, &utils.FileMailer{Dir: mailDir, From: "noreply@example.com"},
+		"http://localhost:8080", 15*time.Minute)
+	mails := func() []string {
+		files, _ := filepath.Glob(filepath.Join(mailDir, "*.eml"))
+		return files
+	}
+
+	// 未注册的邮箱同样返回成功，但不发送邮件
+	assert.NoError(t, magicLinkService.SendMagicLink("nobody@example.com"))
+	assert.Empty(t, mails())
+
+	assert.NoError(t, magicLinkService.SendMagicLink(user.Email))
+	files := mails()
+	if !assert.Len(t, files, 1) {
+		t.FailNow()
+	}
+	content, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: magic@example.com")
+	match := regexp.MustCompile(`/login/magic\?token=(\S+)`).FindStringSubmatch(string(content))
+	if !assert.Len(t, match, 2) {
+		t.FailNow()
+	}
+	token, err := url.QueryUnescape(match[1])
+	assert.NoError(t, err)
+
+	// 频繁请求时不再发送
+	assert.NoError(t, magicLinkService.SendMagicLink(user.Email))
+	assert.Len(t, mails(), 1)
+
+	// 凭邮件中的令牌登录，同时完成邮箱验证；令牌只能使用一次
+	loggedIn, err := magicLinkService.Redeem(token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+	assert.NotNil(t, loggedIn.EmailVerifiedAt)
+	_, err = magicLinkService.Redeem(token)
+	assertAppErrorCode(t, err, 401)
+	_, err = magicLinkService.Redeem("invalid-token")
+	assertAppErrorCode(t, err, 401)
+
+	// 发送后改为别的邮箱：仍可凭链接登录，但新邮箱不会因此通过验证
+	db.Model(&models.MagicLinkToken{}).Where("user_id = ?", user.ID).Update("created_at", time.Now().Add(-time.Hour))
+	assert.NoError(t, magicLinkService.SendMagicLink(user.Email))
+	files = mails()
+	if !assert.Len(t, files, 2) {
+		t.FailNow()
+	}
+	var latest string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		if match := regexp.MustCompile(`/login/magic\?token=(\S+)`).FindStringSubmatch(string(content)); len(match) == 2 && match[1] != url.QueryEscape(token) {
+			latest, err = url.QueryUnescape(match[1])
+			assert.NoError(t, err)
+		}
+	}
+	updated, err := userService.UpdateUser(user.ID, models.UpdateUserRequest{Email: "someone-else@example.com"})
+	assert.NoError(t, err)
+	assert.Nil(t, updated.EmailVerifiedAt)
+	loggedIn, err = magicLinkService.Redeem(latest)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+	assert.Nil(t, loggedIn.EmailVerifiedAt)
+}
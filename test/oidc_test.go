@@ -0,0 +1,252 @@
+package test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/utils"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试用 OIDC 提供方：授权请求直接同意，ID Token 使用 RS256 签名
+type testOIDCProvider struct {
+	server        *httptest.Server
+	key           *rsa.PrivateKey
+	clientID      string
+	clientSecret  string
+	subject       string
+	email         string
+	emailVerified bool
+
+	mu    sync.Mutex
+	codes map[string]url.Values // 授权码 -> 授权请求参数
+}
+
+func newTestOIDCProvider(t *testing.T) *testOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testOIDCProvider{
+		key:           key,
+		clientID:      "blog-test",
+		clientSecret:  "blog-test-secret",
+		subject:       "sso-user-1",
+		email:         "alice@example.com",
+		emailVerified: true,
+		codes:         make(map[string]url.Values),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwk, _ := utils.PublicKeyToJWK("test-key", "RS256", &p.key.PublicKey)
+		json.NewEncoder(w).Encode(utils.JWKS{Keys: []utils.JWK{jwk}})
+	})
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *testOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != p.clientID || query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	code, _ := utils.GenerateRandomToken(16)
+	p.mu.Lock()
+	p.codes[code] = query
+	p.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := url.Values{"code": {code}, "state": {query.Get("state")}}
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *testOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != p.clientID || clientSecret != p.clientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	r.ParseForm()
+	p.mu.Lock()
+	request, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !ok || request.Get("redirect_uri") != r.PostForm.Get("redirect_uri") {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+	// PKCE：code_verifier 的摘要必须与授权请求中的 code_challenge 一致
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != request.Get("code_challenge") {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                p.server.URL,
+		"sub":                p.subject,
+		"aud":                p.clientID,
+		"exp":                now.Add(time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              request.Get("nonce"),
+		"email":              p.email,
+		"email_verified":     p.emailVerified,
+		"preferred_username": "alice",
+	})
+	token.Header["kid"] = "test-key"
+	idToken, _ := token.SignedString(p.key)
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "opaque",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+// 走一遍完整的 SSO 登录：发起 -> 提供方授权 -> 回调，返回回调的响应
+func oidcLogin(t *testing.T, r *gin.Engine) (*httptest.ResponseRecorder, string) {
+	path, stateCookie := oidcAuthorize(t, r)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	req.AddCookie(stateCookie)
+	r.ServeHTTP(w, req)
+	return w, path
+}
+
+// 发起登录并在提供方"同意授权"，返回回调地址和发起登录时写入的 state Cookie
+func oidcAuthorize(t *testing.T, r *gin.Engine) (string, *http.Cookie) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/users/oidc/login", nil)
+	r.ServeHTTP(w, req)
+	if !assert.Equal(t, http.StatusFound, w.Code) {
+		t.FailNow()
+	}
+	var stateCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "oidc_state" {
+			stateCookie = cookie
+		}
+	}
+	if !assert.NotNil(t, stateCookie) {
+		t.FailNow()
+	}
+	assert.True(t, stateCookie.HttpOnly)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	return callback.Path + "?" + callback.RawQuery, stateCookie
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+
+	cfg := config.Load()
+	cfg.OIDC = config.OIDCConfig{
+		Enabled:      true,
+		Issuer:       provider.server.URL,
+		ClientID:     provider.clientID,
+		ClientSecret: provider.clientSecret,
+		RedirectURL:  "http://localhost:8080/api/v1/users/oidc/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+		AllowSignup:  true,
+	}
+	// 创建测试数据库
+	db := setupTestDB(t)
+	router := setupTestHandlerRouter(cfg, db)
+
+	// 测试完毕后，清空数据库
+	defer config.CleanupDB(db)
+
+	log.Print("*****************************")
+	log.Print("OIDC 登录测试 START")
+	log.Print("*****************************")
+
+	// 首次登录：创建本地用户并关联外部身份
+	w, callbackPath := oidcLogin(t, router)
+	assert.Equal(t, 200, w.Code)
+	var response struct {
+		Data struct {
+			Token string              `json:"token"`
+			User  models.UserResponse `json:"user"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NotEmpty(t, response.Data.Token)
+	assert.Equal(t, "alice", response.Data.User.Username)
+	assert.True(t, response.Data.User.EmailVerified)
+
+	// 签发的是普通的访问令牌
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/users/me", nil)
+	req.Header.Set("Authorization", "Bearer "+response.Data.Token)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	// state 只能使用一次
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", callbackPath, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+
+	// 登录 CSRF：回调请求没有携带发起登录的浏览器中的 state Cookie，或 Cookie 不一致时拒绝
+	path, stateCookie := oidcAuthorize(t, router)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", path, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", path, nil)
+	req.AddCookie(&http.Cookie{Name: stateCookie.Name, Value: "attacker-state"})
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+
+	// 再次登录：使用已关联的用户
+	w, _ = oidcLogin(t, router)
+	assert.Equal(t, 200, w.Code)
+	var users, identities int64
+	db.Model(&models.User{}).Count(&users)
+	db.Model(&models.UserIdentity{}).Count(&identities)
+	assert.Equal(t, int64(1), users)
+	assert.Equal(t, int64(1), identities)
+
+	// 另一个外部账号使用了已注册的邮箱，但提供方未验证该邮箱：拒绝关联
+	provider.subject = "sso-user-2"
+	provider.emailVerified = false
+	w, _ = oidcLogin(t, router)
+	assert.Equal(t, 409, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "Email already registered"))
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"gin-examples/project/config"
+	"gin-examples/project/middleware"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// 修改、重置密码前签发的令牌：登录令牌、刷新令牌、个人访问令牌
+type issuedTestTokens struct {
+	tokenService *services.TokenService
+	router       *gin.Engine
+	pair         *models.TokenPair
+	accessToken  string
+}
+
+func issueTestTokens(t *testing.T, db *gorm.DB, user *models.User) *issuedTestTokens {
+	jwtOptions := &utils.JWTOptions{Secret: []byte("test-secret"), Expire: time.Minute}
+	tokenService := services.NewTokenService(db, jwtOptions, time.Hour, services.NewMemoryRevocationStore())
+	pair, err := tokenService.IssueTokens(user, "Go-http-client/1.1", "127.0.0.1")
+	assert.NoError(t, err)
+	created, err := services.NewAccessTokenService(db).CreateAccessToken(user.ID, models.CreateAccessTokenRequest{
+		Name:   "ci",
+		Scopes: []string{models.ScopePostsRead},
+	})
+	assert.NoError(t, err)
+
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/login-only", middleware.Auth(tokenService), ok)
+	router.GET("/scoped", middleware.AuthWithScope(tokenService, models.ScopePostsRead), ok)
+	return &issuedTestTokens{tokenService: tokenService, router: router, pair: pair, accessToken: created.Token}
+}
+
+func (tokens *issuedTestTokens) status(path, token string) int {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	tokens.router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func (tokens *issuedTestTokens) assertValid(t *testing.T) {
+	assert.Equal(t, http.StatusOK, tokens.status("/login-only", tokens.pair.AccessToken))
+	assert.Equal(t, http.StatusOK, tokens.status("/scoped", tokens.accessToken))
+}
+
+// 令牌全部失效：访问令牌、个人访问令牌被中间件拒绝，刷新令牌不能再刷新
+func (tokens *issuedTestTokens) assertRevoked(t *testing.T) {
+	assert.Equal(t, http.StatusUnauthorized, tokens.status("/login-only", tokens.pair.AccessToken))
+	assert.Equal(t, http.StatusUnauthorized, tokens.status("/scoped", tokens.accessToken))
+	_, err := tokens.tokenService.RefreshTokens(tokens.pair.RefreshToken)
+	assertAppErrorCode(t, err, 401)
+}
+
+func TestPasswordService_ForgotAndReset(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+	tokens := issueTestTokens(t, db, user)
+	tokens.assertValid(t)
+
+	userService := services.NewUserService(db, nil, services.PasswordPolicy{MinLength: 8}, false)
+	mailDir := t.TempDir()
+	passwordService := services.NewPasswordService(db, userService, &utils.FileMailer{Dir: mailDir, From: "noreply@example.com"},
+		"http://localhost:8080", 30*time.Minute)
+
+	// 发送重置邮件，从邮件中取出令牌
+	sendResetMail := func() string {
+		assert.NoError(t, passwordService.ForgotPassword(user.Email))
+		files, _ := filepath.Glob(filepath.Join(mailDir, "*.eml"))
+		if !assert.NotEmpty(t, files) {
+			t.FailNow()
+		}
+		for _, file := range files {
+			defer os.Remove(file)
+		}
+		content, err := os.ReadFile(files[len(files)-1])
+		assert.NoError(t, err)
+		match := regexp.MustCompile(`/reset-password\?token=(\S+)`).FindStringSubmatch(string(content))
+		if !assert.Len(t, match, 2) {
+			t.FailNow()
+		}
+		token, err := url.QueryUnescape(match[1])
+		assert.NoError(t, err)
+		return token
+	}
+
+	// 未注册的邮箱同样返回成功，但不发送邮件
+	assert.NoError(t, passwordService.ForgotPassword("nobody@example.com"))
+	files, _ := filepath.Glob(filepath.Join(mailDir, "*.eml"))
+	assert.Empty(t, files)
+
+	// 令牌只保存摘要
+	first := sendResetMail()
+	var stored models.PasswordResetToken
+	assert.NoError(t, db.Where("user_id = ?", user.ID).First(&stored).Error)
+	assert.Equal(t, utils.HashToken(first), stored.TokenHash)
+	assert.NotContains(t, stored.TokenHash, first)
+
+	// 再次发送后旧令牌作废
+	second := sendResetMail()
+	assertAppErrorCode(t, passwordService.ResetPassword(first, "newpassword"), 400)
+
+	// 过期令牌不能使用
+	db.Model(&models.PasswordResetToken{}).Where("token_hash = ?", utils.HashToken(second)).Update("expires_at", time.Now().Add(-time.Minute))
+	assertAppErrorCode(t, passwordService.ResetPassword(second, "newpassword"), 400)
+
+	// 不满足密码策略时令牌不会被消耗
+	third := sendResetMail()
+	assertAppErrorCode(t, passwordService.ResetPassword(third, "short"), 400)
+	tokens.assertValid(t)
+
+	// 重置成功：令牌只能使用一次，新密码可以登录，此前签发的令牌全部失效
+	assert.NoError(t, passwordService.ResetPassword(third, "newpassword"))
+	assertAppErrorCode(t, passwordService.ResetPassword(third, "another-password"), 400)
+	assertAppErrorCode(t, passwordService.ResetPassword("invalid-token", "newpassword"), 400)
+	_, err = userService.Authenticate(user.Username, "newpassword", "127.0.0.1")
+	assert.NoError(t, err)
+	tokens.assertRevoked(t)
+}
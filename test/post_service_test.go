@@ -4,8 +4,10 @@ import (
 	"gin-examples/project/config"
 	"gin-examples/project/models"
 	"gin-examples/project/services"
+	"gin-examples/project/utils"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
@@ -38,6 +40,179 @@ func TestPostService_CreatePost(t *testing.T) {
 	assert.Equal(t, req.Content, post.Content)
 }
 
+func TestPostService_PublishDuePosts(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	postService := services.NewPostService(db)
+	publishAt := time.Now().Add(time.Hour)
+	post, err := postService.CreatePost(user.ID, models.CreatePostRequest{
+		Title:     "scheduled",
+		Content:   "hello world",
+		PublishAt: &publishAt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.PostStatusScheduled, post.Status)
+
+	// 未到期：匿名用户不可见，不发布
+	_, err = postService.GetPostById(post.ID, 0)
+	assert.Error(t, err)
+	published, err := postService.PublishDuePosts()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	// 模拟停机期间到期：只发布一次
+	db.Model(&models.Post{}).Where("id = ?", post.ID).Update("publish_at", time.Now().Add(-time.Minute))
+	published, err = postService.PublishDuePosts()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	published, err = postService.PublishDuePosts()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	got, err := postService.GetPostById(post.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PostStatusPublished, got.Status)
+	assert.NotNil(t, got.PublishedAt)
+}
+
+func TestPostService_ListPostAllQuery(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	postService := services.NewPostService(db)
+	_, err = postService.CreatePost(user.ID, models.CreatePostRequest{Title: "gin 入门", Content: "hello", Tags: []string{"go"}})
+	assert.NoError(t, err)
+	_, err = postService.CreatePost(user.ID, models.CreatePostRequest{Title: "gin 草稿", Content: "hello", Tags: []string{"draft"}})
+	assert.NoError(t, err)
+
+	page, err := postService.ListPostAll(0, "title:gin author:"+user.Username+" comments:0 -tag:draft", 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, "gin 入门", page.Posts[0].Title)
+
+	// 语法错误返回 422，指出出错位置
+	_, err = postService.ListPostAll(0, "title:gin (tag:go", 1, 10)
+	var appErr *utils.AppError
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 422, appErr.Code)
+	assert.Equal(t, 10, appErr.Details.(*utils.QueryError).Position)
+}
+
+func TestPostService_ListPostByTaxonomy(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	// 分类树：tech > go > web，另有根分类 life
+	categoryService := services.NewCategoryService(db)
+	tech, err := categoryService.CreateCategory(models.CreateCategoryRequest{Name: "Tech", Slug: "tech"})
+	assert.NoError(t, err)
+	golang, err := categoryService.CreateCategory(models.CreateCategoryRequest{Name: "Go", Slug: "go", ParentID: &tech.ID})
+	assert.NoError(t, err)
+	web, err := categoryService.CreateCategory(models.CreateCategoryRequest{Name: "Web", Slug: "web", ParentID: &golang.ID})
+	assert.NoError(t, err)
+	life, err := categoryService.CreateCategory(models.CreateCategoryRequest{Name: "Life", Slug: "life"})
+	assert.NoError(t, err)
+
+	postService := services.NewPostService(db)
+	create := func(title string, tags []string, categoryID *uint) {
+		_, err := postService.CreatePost(user.ID, models.CreatePostRequest{Title: title, Content: "hello", Tags: tags, CategoryID: categoryID})
+		assert.NoError(t, err)
+	}
+	create("tech", []string{"go"}, &tech.ID)
+	create("go", []string{"go", "web"}, &golang.ID)
+	create("web", []string{"Web"}, &web.ID)
+	create("life", []string{"travel"}, &life.ID)
+	create("none", []string{"go_web", "goxweb"}, nil)
+
+	list := func(req models.ListPostRequest) []string {
+		req.PageNo, req.PageSize = 1, 10
+		posts, err := postService.ListPostByCondition(req)
+		assert.NoError(t, err)
+		titles := make([]string, 0, len(posts))
+		for _, post := range posts {
+			titles = append(titles, post.Title)
+		}
+		return titles
+	}
+
+	// 任意一个标签（标签名不区分大小写）
+	assert.ElementsMatch(t, []string{"tech", "go", "web"}, list(models.ListPostRequest{TagsAny: []string{"GO", "web"}}))
+	// 全部标签，重复的标签只算一次
+	assert.ElementsMatch(t, []string{"go"}, list(models.ListPostRequest{TagsAll: []string{"go", "web", "Go"}}))
+	assert.Empty(t, list(models.ListPostRequest{TagsAll: []string{"go", "unknown"}}))
+	// 分类包含全部子分类
+	assert.ElementsMatch(t, []string{"tech", "go", "web"}, list(models.ListPostRequest{CategoryID: &tech.ID}))
+	assert.ElementsMatch(t, []string{"go", "web"}, list(models.ListPostRequest{CategoryID: &golang.ID}))
+	assert.ElementsMatch(t, []string{"web"}, list(models.ListPostRequest{CategoryID: &web.ID}))
+	// 条件同时满足
+	assert.ElementsMatch(t, []string{"go"}, list(models.ListPostRequest{TagsAny: []string{"go"}, CategoryID: &golang.ID}))
+
+	// 标签前缀：_ 和 % 按普通字符匹配
+	tagService := services.NewTagService(db)
+	tagNames := func(prefix string) []string {
+		page, err := tagService.ListTags(prefix, 1, 10)
+		assert.NoError(t, err)
+		names := make([]string, 0, len(page.Tags))
+		for _, tag := range page.Tags {
+			names = append(names, tag.Name)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"go", "go_web", "goxweb"}, tagNames("GO"))
+	assert.ElementsMatch(t, []string{"go_web"}, tagNames("go_"))
+	assert.Empty(t, tagNames("%"))
+
+	// 子树移动后按新的位置筛选
+	_, err = categoryService.UpdateCategory(golang.ID, models.UpdateCategoryRequest{Name: "Go", Slug: "go", ParentID: &life.ID})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"tech"}, list(models.ListPostRequest{CategoryID: &tech.ID}))
+	assert.ElementsMatch(t, []string{"life", "go", "web"}, list(models.ListPostRequest{CategoryID: &life.ID}))
+}
+
+func TestSearchService_Search(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	searcher := services.NewSearcher(db)
+	assert.NoError(t, searcher.Setup())
+	searchService := services.NewSearchService(searcher)
+	postService := services.NewPostService(db)
+
+	post, err := postService.CreatePost(user.ID, models.CreatePostRequest{Title: "Go 并发编程", Content: "goroutine 与 channel"})
+	assert.NoError(t, err)
+	_, err = postService.CreatePost(user.ID, models.CreatePostRequest{Title: "草稿", Content: "并发编程草稿", Status: models.PostStatusDraft})
+	assert.NoError(t, err)
+
+	// 只搜索已发布的文章，命中部分高亮
+	result, err := searchService.Search("并发编程", models.SearchTypePost, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Total)
+	assert.Equal(t, "Go <mark>并发编程</mark>", result.Hits[0].Title)
+
+	// 修改后索引同步
+	_, err = postService.UpdatePost(user.ID, models.UpdatePostRequest{ID: post.ID, Title: "Go 内存模型", Content: "happens-before"})
+	assert.NoError(t, err)
+	result, err = searchService.Search("并发编程", models.SearchTypePost, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Total)
+	result, err = searchService.Search("内存模型", models.SearchTypePost, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Total)
+}
+
 func setupTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
 
@@ -58,7 +233,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	- **不会删除索引**
 	- 追加 uniqueIndex 时，若是数据中存在重复的数据，那么启动失败。
 	*/
-	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.Role{}, &models.Permission{}, &models.SigningKey{}, &models.PasswordResetToken{}, &models.LoginAttempt{}, &models.TwoFactor{}, &models.RecoveryCode{}, &models.PersonalAccessToken{}, &models.UserIdentity{}, &models.OIDCLoginState{}, &models.Session{}, &models.MagicLinkToken{}, &models.WebAuthnCredential{}, &models.WebAuthnChallenge{}, &models.Invite{}, &models.InviteRedemption{}, &models.PostRevision{}, &models.Tag{}, &models.Category{}); err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 	return db
@@ -66,7 +241,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 
 func setupTestServicePostData(db *gorm.DB) (*models.User, error) {
 	// 测试文章，预先创建初始测试账户，显式设置 ID 为 1 和 2，确保每次测试都使用相同的 ID
-	userService := services.NewUserService(db)
+	userService := services.NewUserService(db, nil, services.PasswordPolicy{}, false)
 	req := models.CreateUserRequest{
 		Username: "admin",
 		Email:    "admin@example.com",
@@ -76,6 +251,10 @@ func setupTestServicePostData(db *gorm.DB) (*models.User, error) {
 	if err != nil {
 		log.Fatalf("Failed to create test user:admin: %v", err)
 	}
+	// 测试账户直接标记邮箱已验证，否则无法发表文章和评论
+	if err := db.Model(user).Update("email_verified_at", time.Now()).Error; err != nil {
+		log.Fatalf("Failed to verify test user email:admin: %v", err)
+	}
 	log.Printf("User admin create success userId:%d, username=%s", user.ID, user.Username)
 	return user, nil
 }
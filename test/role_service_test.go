@@ -0,0 +1,57 @@
+package test
+
+import (
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleService_BootstrapAdminAndPermissions(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	roleService := services.NewRoleService(db)
+	assert.NoError(t, roleService.SeedDefaultRoles())
+
+	// 没有管理员时把指定用户设为 admin，之后不再生效
+	promoted, err := roleService.BootstrapAdmin(user.Username)
+	assert.NoError(t, err)
+	assert.True(t, promoted)
+	promoted, err = roleService.BootstrapAdmin(user.Username)
+	assert.NoError(t, err)
+	assert.False(t, promoted)
+
+	var admin models.User
+	assert.NoError(t, db.First(&admin, user.ID).Error)
+	assert.Equal(t, models.RoleAdmin, admin.Role)
+
+	jwtOptions := &utils.JWTOptions{Secret: []byte("test-secret"), Expire: time.Minute}
+	tokenService := services.NewTokenService(db, jwtOptions, time.Hour, services.NewMemoryRevocationStore())
+	pair, err := tokenService.IssueTokens(&admin, "Go-http-client/1.1", "127.0.0.1")
+	assert.NoError(t, err)
+	claims, err := tokenService.ValidateAccessToken(pair.AccessToken)
+	assert.NoError(t, err)
+	assert.Contains(t, claims.Permissions, models.PermUsersManage)
+
+	// 修改角色权限后旧令牌失效，刷新后得到新的权限
+	_, err = roleService.UpdateRolePermissions(models.RoleAdmin, []string{"unknown"})
+	assertAppErrorCode(t, err, 400)
+	// 重复的权限不算未知权限
+	_, err = roleService.UpdateRolePermissions(models.RoleAdmin, []string{models.PermRolesManage, models.PermRolesManage})
+	assert.NoError(t, err)
+	_, err = tokenService.ValidateAccessToken(pair.AccessToken)
+	assert.Error(t, err)
+	refreshed, err := tokenService.RefreshTokens(pair.RefreshToken)
+	assert.NoError(t, err)
+	claims, err = tokenService.ValidateAccessToken(refreshed.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{models.PermRolesManage}, claims.Permissions)
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gin-examples/project/config"
+	"gin-examples/project/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieSession_AuthModeAndCSRF(t *testing.T) {
+	cfg := config.Load()
+	cfg.Server.AuthMode = "cookie"
+	cfg.Server.CORS.AllowedOrigins = []string{"https://blog.example.com"}
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	assert.NoError(t, services.NewRoleService(db).SeedDefaultRoles())
+	router := setupTestHandlerRouter(cfg, db)
+	_, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	login := func(authMode string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/users/login", bytes.NewBufferString(`{"username": "admin", "password": "admin123"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if authMode != "" {
+			req.Header.Set("X-Auth-Mode", authMode)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未指定 X-Auth-Mode：令牌在响应体中返回，不写 Cookie
+	w := login("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"refresh_token"`)
+	assert.Empty(t, w.Result().Cookies())
+
+	// X-Auth-Mode: cookie：令牌只写入 Cookie
+	w = login("cookie")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"refresh_token"`)
+	var response struct {
+		Data struct {
+			CSRFToken string `json:"csrf_token"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 3)
+
+	logout := func(csrfToken string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/users/logout", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		if csrfToken != "" {
+			req.Header.Set("X-CSRF-Token", csrfToken)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 使用 Cookie 凭证的 POST：缺少或伪造 X-CSRF-Token 时 403
+	assert.Equal(t, http.StatusForbidden, logout(""))
+	assert.Equal(t, http.StatusForbidden, logout("forged"))
+	assert.Equal(t, http.StatusOK, logout(response.Data.CSRFToken))
+
+	// 跨域：只有允许的来源可以携带凭证，其他来源只能不带凭证访问
+	cors := func(origin string) http.Header {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("OPTIONS", "/api/v1/users/login", nil)
+		req.Header.Set("Origin", origin)
+		router.ServeHTTP(w, req)
+		return w.Header()
+	}
+	allowed := cors("https://blog.example.com")
+	assert.Equal(t, "https://blog.example.com", allowed.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", allowed.Get("Access-Control-Allow-Credentials"))
+	other := cors("https://other.example.com")
+	assert.Equal(t, "*", other.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, other.Get("Access-Control-Allow-Headers"), "Authorization")
+	assert.Empty(t, other.Get("Access-Control-Allow-Credentials"))
+}
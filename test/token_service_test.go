@@ -0,0 +1,139 @@
+package test
+
+import (
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenService_RefreshTokens(t *testing.T) {
+	// 创建测试数据库
+	db := setupTestDB(t)
+
+	// 测试完毕后，清空数据库
+	defer config.CleanupDB(db)
+
+	log.Print("*****************************")
+	log.Print("刷新令牌测试 START")
+	log.Print("*****************************")
+
+	// 初始化测试数据
+	user, _ := setupTestServicePostData(db)
+
+	// 本次测试
+	jwtOptions := &utils.JWTOptions{Secret: []byte("test-secret"), Expire: time.Minute}
+	tokenService := services.NewTokenService(db, jwtOptions, time.Hour, services.NewMemoryRevocationStore())
+	pair, err := tokenService.IssueTokens(user, "Go-http-client/1.1", "127.0.0.1")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, pair.AccessToken)
+	assert.NotEmpty(t, pair.RefreshToken)
+
+	// 正常轮换
+	rotated, err := tokenService.RefreshTokens(pair.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, pair.RefreshToken, rotated.RefreshToken)
+
+	// 重放已轮换的刷新令牌：拒绝，并且整族作废
+	_, err = tokenService.RefreshTokens(pair.RefreshToken)
+	assert.Error(t, err)
+	_, err = tokenService.RefreshTokens(rotated.RefreshToken)
+	assert.Error(t, err)
+}
+
+func TestTokenService_ConfigAndLeeway(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	// 有效期、时钟偏差来自配置，格式错误时使用默认值
+	jwtConfig := config.JWTConfig{Expire: "10m", RefreshExpire: "2h", Leeway: "30s", Issuer: "blog-api-test", Audience: "blog-api"}
+	assert.Equal(t, 10*time.Minute, jwtConfig.ExpireDuration())
+	assert.Equal(t, 2*time.Hour, jwtConfig.RefreshExpireDuration())
+	assert.Equal(t, 30*time.Second, jwtConfig.LeewayDuration())
+	assert.Equal(t, 15*time.Minute, config.JWTConfig{Expire: "abc"}.ExpireDuration())
+
+	jwtOptions := &utils.JWTOptions{
+		Secret:   []byte("test-secret"),
+		Expire:   jwtConfig.ExpireDuration(),
+		Issuer:   jwtConfig.Issuer,
+		Audience: jwtConfig.Audience,
+		Leeway:   jwtConfig.LeewayDuration(),
+	}
+	tokenService := services.NewTokenService(db, jwtOptions, jwtConfig.RefreshExpireDuration(), services.NewMemoryRevocationStore())
+	pair, err := tokenService.IssueTokens(user, "Go-http-client/1.1", "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(600), pair.ExpiresIn)
+
+	var refresh models.RefreshToken
+	assert.NoError(t, db.Where("token_hash = ?", utils.HashToken(pair.RefreshToken)).First(&refresh).Error)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), refresh.ExpiresAt, time.Minute)
+
+	// 过期 10 秒的令牌在 30 秒偏差内仍有效，不允许偏差时无效
+	expired, err := utils.GenerateTokenWithExpire(jwtOptions, utils.Claims{UserID: user.ID}, -10*time.Second)
+	assert.NoError(t, err)
+	_, err = utils.ParseToken(expired, jwtOptions)
+	assert.NoError(t, err)
+	strict := *jwtOptions
+	strict.Leeway = 0
+	_, err = utils.ParseToken(expired, &strict)
+	assert.Error(t, err)
+
+	// 其他部署（issuer 不同）签发的令牌无效
+	other := *jwtOptions
+	other.Issuer = "blog-api-prod"
+	_, err = utils.ParseToken(pair.AccessToken, &other)
+	assert.Error(t, err)
+
+	// 刷新令牌过期后不能再换取令牌
+	db.Model(&refresh).Update("expires_at", time.Now().Add(-time.Minute))
+	_, err = tokenService.RefreshTokens(pair.RefreshToken)
+	assert.Error(t, err)
+}
+
+func TestTokenService_LogoutAll(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	jwtOptions := &utils.JWTOptions{Secret: []byte("test-secret"), Expire: time.Minute}
+	tokenService := services.NewTokenService(db, jwtOptions, time.Hour, services.NewMemoryRevocationStore())
+	laptop, err := tokenService.IssueTokens(user, "laptop", "127.0.0.1")
+	assert.NoError(t, err)
+	phone, err := tokenService.IssueTokens(user, "phone", "127.0.0.2")
+	assert.NoError(t, err)
+
+	// 退出单个设备：只影响当前访问令牌和令牌族
+	claims, err := tokenService.ValidateAccessToken(laptop.AccessToken)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenService.Logout(claims, laptop.RefreshToken))
+	_, err = tokenService.ValidateAccessToken(laptop.AccessToken)
+	assert.Error(t, err)
+	_, err = tokenService.RefreshTokens(laptop.RefreshToken)
+	assert.Error(t, err)
+	_, err = tokenService.ValidateAccessToken(phone.AccessToken)
+	assert.NoError(t, err)
+
+	// 退出所有设备：其他设备的访问令牌、刷新令牌全部失效，之后重新登录不受影响
+	assert.NoError(t, tokenService.LogoutAll(user.ID))
+	_, err = tokenService.ValidateAccessToken(phone.AccessToken)
+	assert.Error(t, err)
+	_, err = tokenService.RefreshTokens(phone.RefreshToken)
+	assert.Error(t, err)
+
+	var reloaded models.User
+	assert.NoError(t, db.First(&reloaded, user.ID).Error)
+	again, err := tokenService.IssueTokens(&reloaded, "laptop", "127.0.0.1")
+	assert.NoError(t, err)
+	_, err = tokenService.ValidateAccessToken(again.AccessToken)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorService_SetupAndLogin(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	jwtOptions := &utils.JWTOptions{Secret: []byte("test-secret"), Expire: time.Minute}
+	tokenService := services.NewTokenService(db, jwtOptions, time.Hour, services.NewMemoryRevocationStore())
+	twoFactorService := services.NewTwoFactorService(db, tokenService, nil, []byte("0123456789abcdef0123456789abcdef"), "Blog", time.Minute)
+
+	// 开始绑定：密钥加密保存
+	setup, err := twoFactorService.Setup(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(setup.ProvisioningURI, "otpauth://totp/"))
+	var stored models.TwoFactor
+	assert.NoError(t, db.Where("user_id = ?", user.ID).First(&stored).Error)
+	assert.NotContains(t, stored.Secret, setup.Secret)
+
+	// 确认绑定：验证码错误时不开启，正确时返回 10 个恢复码
+	_, err = twoFactorService.Enable(user.ID, "000000")
+	assert.Error(t, err)
+	step := utils.TOTPStep(time.Now())
+	code, err := utils.TOTPCode(setup.Secret, step)
+	assert.NoError(t, err)
+	recoveryCodes, err := twoFactorService.Enable(user.ID, code)
+	assert.NoError(t, err)
+	assert.Len(t, recoveryCodes, 10)
+	enabled, err := twoFactorService.IsEnabled(user.ID)
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	// 登录挑战：已使用过的验证码被拒绝，下一个时间步的验证码通过，挑战令牌只能使用一次
+	challenge, err := twoFactorService.IssueChallenge(user)
+	assert.NoError(t, err)
+	assert.True(t, challenge.TwoFactorRequired)
+	_, err = twoFactorService.CompleteLogin(challenge.ChallengeToken, code, "127.0.0.1")
+	assert.Error(t, err)
+	nextCode, err := utils.TOTPCode(setup.Secret, step+1)
+	assert.NoError(t, err)
+	loggedIn, err := twoFactorService.CompleteLogin(challenge.ChallengeToken, nextCode, "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+	_, err = twoFactorService.CompleteLogin(challenge.ChallengeToken, recoveryCodes[0], "127.0.0.1")
+	assert.Error(t, err)
+
+	// 挑战令牌不能当作访问令牌
+	_, err = tokenService.ValidateAccessToken(challenge.ChallengeToken)
+	assert.Error(t, err)
+
+	// 恢复码代替验证码登录，只能使用一次
+	challenge, err = twoFactorService.IssueChallenge(user)
+	assert.NoError(t, err)
+	_, err = twoFactorService.CompleteLogin(challenge.ChallengeToken, recoveryCodes[0], "127.0.0.1")
+	assert.NoError(t, err)
+	challenge, err = twoFactorService.IssueChallenge(user)
+	assert.NoError(t, err)
+	_, err = twoFactorService.CompleteLogin(challenge.ChallengeToken, recoveryCodes[0], "127.0.0.1")
+	assert.Error(t, err)
+	status, err := twoFactorService.Status(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), status.RecoveryCodesRemaining)
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserHandler_ChangePassword(t *testing.T) {
+	cfg := config.Load()
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	assert.NoError(t, services.NewRoleService(db).SeedDefaultRoles())
+	router := setupTestHandlerRouter(cfg, db)
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	request := func(method, path, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	var login struct {
+		Data struct {
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
+		} `json:"data"`
+	}
+	w := request("POST", "/api/v1/users/login", "", `{"username": "admin", "password": "admin123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &login))
+	pat, err := services.NewAccessTokenService(db).CreateAccessToken(user.ID, models.CreateAccessTokenRequest{
+		Name:   "ci",
+		Scopes: []string{models.ScopePostsRead},
+	})
+	assert.NoError(t, err)
+
+	// 当前密码错误、不满足密码策略：拒绝，令牌仍然有效
+	w = request("PUT", "/api/v1/users/me/password", login.Data.Token, `{"current_password": "wrong123", "new_password": "newpassword1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Current password is incorrect")
+	w = request("PUT", "/api/v1/users/me/password", login.Data.Token, `{"current_password": "admin123", "new_password": "onlyletters"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "must contain a digit")
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/users/me/tokens", login.Data.Token, "").Code)
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/posts/me", pat.Token, "").Code)
+
+	// 修改成功：返回新的令牌，此前签发的访问令牌、刷新令牌、个人访问令牌全部失效
+	w = request("PUT", "/api/v1/users/me/password", login.Data.Token, `{"current_password": "admin123", "new_password": "newpassword1"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var changed struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &changed))
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/users/me/tokens", changed.Data.Token, "").Code)
+
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/api/v1/users/me/tokens", login.Data.Token, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/api/v1/posts/me", pat.Token, "").Code)
+	w = request("POST", "/api/v1/users/token/refresh", "", `{"refresh_token": "`+login.Data.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 新密码可以登录，旧密码不能
+	assert.Equal(t, http.StatusOK, request("POST", "/api/v1/users/login", "", `{"username": "admin", "password": "newpassword1"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, request("POST", "/api/v1/users/login", "", `{"username": "admin", "password": "admin123"}`).Code)
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"gin-examples/project/services"
+	"gin-examples/project/utils"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserService_GrandfatherVerifiedEmails(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	// 升级前注册的用户没有验证时间
+	user, err := services.NewUserService(db, nil, services.PasswordPolicy{}, false).CreateUser(models.CreateUserRequest{
+		Username: "legacy",
+		Email:    "legacy@example.com",
+		Password: "legacy123",
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, user.EmailVerifiedAt)
+
+	count, err := services.GrandfatherVerifiedEmails(db)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	var migrated models.User
+	assert.NoError(t, db.First(&migrated, user.ID).Error)
+	if assert.NotNil(t, migrated.EmailVerifiedAt) {
+		assert.True(t, migrated.EmailVerifiedAt.Equal(migrated.CreatedAt))
+	}
+	post, err := services.NewPostService(db).CreatePost(user.ID, models.CreatePostRequest{Title: "Hello", Content: "World"})
+	assert.NoError(t, err)
+	assert.NotZero(t, post.ID)
+}
+
+func TestUserService_LoginLockout(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+
+	guard := services.NewLoginGuard(services.NewGormLoginAttemptStore(db), services.LoginPolicy{
+		MaxAttempts:   3,
+		IPMaxAttempts: 10,
+		Lockout:       time.Minute,
+		Window:        time.Hour,
+	})
+	userService := services.NewUserService(db, guard, services.PasswordPolicy{}, false)
+
+	// 前两次失败只返回凭证错误，第三次触发锁定
+	for i := 0; i < 2; i++ {
+		_, err = userService.Authenticate(user.Username, "wrong-password", "10.0.0.1")
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+	}
+	_, err = userService.Authenticate(user.Username, "wrong-password", "10.0.0.1")
+	assertAppErrorCode(t, err, http.StatusTooManyRequests)
+
+	// 锁定期间正确的密码同样被拒绝（换 IP 也一样）
+	_, err = userService.Authenticate(user.Username, "admin123", "10.0.0.2")
+	assertAppErrorCode(t, err, http.StatusTooManyRequests)
+
+	// 锁定到期后可以登录，计数已清零
+	db.Model(&models.LoginAttempt{}).Where("`key` = ?", "user:"+user.Username).Update("locked_until", time.Now().Add(-time.Second))
+	_, err = userService.Authenticate(user.Username, "admin123", "10.0.0.2")
+	assert.NoError(t, err)
+
+	// 不存在的用户名同样计数并锁定
+	for i := 0; i < 2; i++ {
+		_, err = userService.Authenticate("nobody", "wrong-password", "10.0.0.3")
+		assertAppErrorCode(t, err, http.StatusUnauthorized)
+	}
+	_, err = userService.Authenticate("nobody", "wrong-password", "10.0.0.3")
+	assertAppErrorCode(t, err, http.StatusTooManyRequests)
+
+	// 同一 IP 的失败次数单独累计，未达到 IP 阈值
+	var ipAttempt models.LoginAttempt
+	assert.NoError(t, db.Where("`key` = ?", "ip:10.0.0.3").First(&ipAttempt).Error)
+	assert.Equal(t, 3, ipAttempt.Failures)
+}
+
+func TestUserService_PasswordHashUpgrade(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	hasher := utils.NewArgon2idHasher()
+	services.SetPasswordHasher(hasher)
+	defer services.SetPasswordHasher(utils.NewArgon2idHasher())
+
+	user, err := setupTestServicePostData(db)
+	assert.NoError(t, err)
+	userService := services.NewUserService(db, nil, services.PasswordPolicy{}, false)
+	storedHash := func() string {
+		var stored models.User
+		assert.NoError(t, db.First(&stored, user.ID).Error)
+		return stored.Password
+	}
+
+	// 旧部署的 bcrypt 哈希：可以登录，登录成功后升级为 argon2id
+	bcryptHash, err := (&utils.BcryptHasher{Cost: bcrypt.MinCost}).Hash("admin123")
+	assert.NoError(t, err)
+	db.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("password", bcryptHash)
+	_, err = userService.Authenticate(user.Username, "wrong123", "127.0.0.1")
+	assertAppErrorCode(t, err, 401)
+	assert.Equal(t, bcryptHash, storedHash())
+	_, err = userService.Authenticate(user.Username, "admin123", "127.0.0.1")
+	assert.NoError(t, err)
+	upgraded := storedHash()
+	assert.True(t, strings.HasPrefix(upgraded, "$argon2id$"))
+	assert.False(t, hasher.NeedsRehash(upgraded))
+
+	// 参数过时的 argon2id 哈希同样重新哈希
+	weakHash, err := (&utils.Argon2idHasher{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}).Hash("admin123")
+	assert.NoError(t, err)
+	db.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("password", weakHash)
+	assert.True(t, hasher.NeedsRehash(weakHash))
+	_, err = userService.Authenticate(user.Username, "admin123", "127.0.0.1")
+	assert.NoError(t, err)
+	rehashed := storedHash()
+	assert.NotEqual(t, weakHash, rehashed)
+	assert.Contains(t, rehashed, "$m=65536,t=3,p=2$")
+
+	// 参数一致时不再重新哈希
+	_, err = userService.Authenticate(user.Username, "admin123", "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, rehashed, storedHash())
+}
+
+func TestUserService_ReservedUsernames(t *testing.T) {
+	db := setupTestDB(t)
+	defer config.CleanupDB(db)
+
+	// 与 /users 下固定路由同名的用户名不能注册，否则公开资料页无法访问
+	userService := services.NewUserService(db, nil, services.PasswordPolicy{}, false)
+	for _, username := range []string{"sta", "STA", "me"} {
+		_, err := userService.CreateUser(models.CreateUserRequest{Username: username, Email: username + "@example.com", Password: "reserved123"})
+		assertAppErrorCode(t, err, 400)
+	}
+	_, err := userService.CreateUser(models.CreateUserRequest{Username: "stan", Email: "stan@example.com", Password: "reserved123"})
+	assert.NoError(t, err)
+}
+
+func assertAppErrorCode(t *testing.T, err error, code int) {
+	t.Helper()
+	var appErr *utils.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, code, appErr.Code)
+	}
+}
@@ -0,0 +1,265 @@
+package test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"gin-examples/project/config"
+	"gin-examples/project/models"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试用 CBOR 编码：只支持整数、字节串、文本串和按顺序编码的映射
+type cborEntry struct {
+	key, value interface{}
+}
+
+type cborMap []cborEntry
+
+func cborEncode(value interface{}) []byte {
+	switch v := value.(type) {
+	case int:
+		if v >= 0 {
+			return cborHead(0, uint64(v))
+		}
+		return cborHead(1, uint64(-1-v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case cborMap:
+		out := cborHead(5, uint64(len(v)))
+		for _, entry := range v {
+			out = append(out, cborEncode(entry.key)...)
+			out = append(out, cborEncode(entry.value)...)
+		}
+		return out
+	}
+	panic("unsupported cbor value")
+}
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 1<<8:
+		return []byte{major<<5 | 24, byte(n)}
+	default:
+		return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+	}
+}
+
+// 软件认证器：ES256 密钥，始终报告用户在场、用户已验证
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   string
+	signCount    uint32
+	rpID         string
+	origin       string
+}
+
+func newSoftAuthenticator(t *testing.T, rpID, origin string) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 32)
+	rand.Read(credentialID)
+	return &softAuthenticator{key: key, credentialID: credentialID, rpID: rpID, origin: origin}
+}
+
+func (a *softAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(ceremony, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": a.origin})
+	return data
+}
+
+// navigator.credentials.create()
+func (a *softAuthenticator) create(options models.PublicKeyCredentialCreationOptions) models.PasskeyCredential {
+	a.userHandle = options.User.ID
+	coseKey := cborEncode(cborMap{
+		{1, 2},  // kty: EC2
+		{3, -7}, // alg: ES256
+		{-1, 1}, // crv: P-256
+		{-2, a.key.X.FillBytes(make([]byte, 32))},
+		{-3, a.key.Y.FillBytes(make([]byte, 32))},
+	})
+	attested := make([]byte, 16) // aaguid
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, coseKey...)
+
+	attestationObject := cborEncode(cborMap{
+		{"fmt", "none"},
+		{"attStmt", cborMap{}},
+		{"authData", a.authenticatorData(0x45, attested)}, // UP | UV | AT
+	})
+	encode := base64.RawURLEncoding.EncodeToString
+	return models.PasskeyCredential{
+		ID:   encode(a.credentialID),
+		Type: "public-key",
+		Response: models.PasskeyCredentialResponse{
+			ClientDataJSON:    encode(a.clientData("webauthn.create", options.Challenge)),
+			AttestationObject: encode(attestationObject),
+		},
+	}
+}
+
+// navigator.credentials.get()
+func (a *softAuthenticator) get(options models.PublicKeyCredentialRequestOptions) models.PasskeyCredential {
+	a.signCount++
+	authData := a.authenticatorData(0x05, nil) // UP | UV
+	clientData := a.clientData("webauthn.get", options.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, _ := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+
+	encode := base64.RawURLEncoding.EncodeToString
+	return models.PasskeyCredential{
+		ID:   encode(a.credentialID),
+		Type: "public-key",
+		Response: models.PasskeyCredentialResponse{
+			ClientDataJSON:    encode(clientData),
+			AuthenticatorData: encode(authData),
+			Signature:         encode(signature),
+			UserHandle:        a.userHandle,
+		},
+	}
+}
+
+func passkeyRequest(r *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(data))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+type passkeyOptions[T any] struct {
+	Data struct {
+		SessionID string `json:"session_id"`
+		PublicKey T      `json:"public_key"`
+	} `json:"data"`
+}
+
+// 登录第一步 + 认证器签名，返回第二步的请求体
+func passkeyAssertion(t *testing.T, r *gin.Engine, authenticator *softAuthenticator) models.FinishPasskeyLoginRequest {
+	w := passkeyRequest(r, "POST", "/api/v1/users/login/passkey/begin", "", gin.H{})
+	if !assert.Equal(t, 200, w.Code) {
+		t.FailNow()
+	}
+	var options passkeyOptions[models.PublicKeyCredentialRequestOptions]
+	json.Unmarshal(w.Body.Bytes(), &options)
+	return models.FinishPasskeyLoginRequest{
+		SessionID:  options.Data.SessionID,
+		Credential: authenticator.get(options.Data.PublicKey),
+	}
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	cfg := config.Load()
+	// 创建测试数据库
+	db := setupTestDB(t)
+	router := setupTestHandlerRouter(cfg, db)
+
+	// 测试完毕后，清空数据库
+	defer config.CleanupDB(db)
+
+	log.Print("*****************************")
+	log.Print("通行密钥测试 START")
+	log.Print("*****************************")
+
+	// 注册用户并用密码登录
+	w := passkeyRequest(router, "POST", "/api/v1/users/register", "", models.CreateUserRequest{Username: "carol", Email: "carol@example.com", Password: "carol12345"})
+	assert.Equal(t, 200, w.Code)
+	w = passkeyRequest(router, "POST", "/api/v1/users/login", "", models.LoginRequest{Username: "carol", Password: "carol12345"})
+	var login struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &login)
+	if !assert.NotEmpty(t, login.Data.Token) {
+		t.FailNow()
+	}
+
+	// 注册通行密钥
+	w = passkeyRequest(router, "POST", "/api/v1/users/me/passkeys/register/begin", login.Data.Token, nil)
+	assert.Equal(t, 200, w.Code)
+	var creation passkeyOptions[models.PublicKeyCredentialCreationOptions]
+	json.Unmarshal(w.Body.Bytes(), &creation)
+	assert.Equal(t, cfg.Auth.WebAuthn.RPID, creation.Data.PublicKey.RP.ID)
+
+	authenticator := newSoftAuthenticator(t, cfg.Auth.WebAuthn.RPID, cfg.Server.PublicURL)
+	registration := models.FinishPasskeyRegistrationRequest{
+		SessionID:  creation.Data.SessionID,
+		Name:       "Test key",
+		Credential: authenticator.create(creation.Data.PublicKey),
+	}
+	w = passkeyRequest(router, "POST", "/api/v1/users/me/passkeys/register/finish", login.Data.Token, registration)
+	assert.Equal(t, 200, w.Code)
+
+	// 同一个挑战不能再次使用
+	w = passkeyRequest(router, "POST", "/api/v1/users/me/passkeys/register/finish", login.Data.Token, registration)
+	assert.Equal(t, 400, w.Code)
+
+	// 通行密钥登录，签发的是普通的访问令牌
+	assertion := passkeyAssertion(t, router, authenticator)
+	w = passkeyRequest(router, "POST", "/api/v1/users/login/passkey/finish", "", assertion)
+	assert.Equal(t, 200, w.Code)
+	var passkeyLogin struct {
+		Data struct {
+			Token string              `json:"token"`
+			User  models.UserResponse `json:"user"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &passkeyLogin)
+	assert.Equal(t, "carol", passkeyLogin.Data.User.Username)
+	w = passkeyRequest(router, "GET", "/api/v1/users/me", passkeyLogin.Data.Token, nil)
+	assert.Equal(t, 200, w.Code)
+
+	// 重放同一断言：挑战已被使用
+	w = passkeyRequest(router, "POST", "/api/v1/users/login/passkey/finish", "", assertion)
+	assert.Equal(t, 400, w.Code)
+
+	// 来自其他站点的断言
+	phishing := *authenticator
+	phishing.origin = "https://evil.example.com"
+	w = passkeyRequest(router, "POST", "/api/v1/users/login/passkey/finish", "", passkeyAssertion(t, router, &phishing))
+	assert.Equal(t, 400, w.Code)
+
+	// 签名被篡改
+	assertion = passkeyAssertion(t, router, authenticator)
+	signature, _ := base64.RawURLEncoding.DecodeString(assertion.Credential.Response.Signature)
+	signature[len(signature)-1] ^= 0xff
+	assertion.Credential.Response.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	w = passkeyRequest(router, "POST", "/api/v1/users/login/passkey/finish", "", assertion)
+	assert.Equal(t, 401, w.Code)
+
+	// 计数器回退：认证器可能被克隆
+	authenticator.signCount = 0
+	w = passkeyRequest(router, "POST", "/api/v1/users/login/passkey/finish", "", passkeyAssertion(t, router, authenticator))
+	assert.Equal(t, 401, w.Code)
+}
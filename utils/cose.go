@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// COSE 算法标识（RFC 9053），WebAuthn 凭据公钥使用
+const (
+	COSEAlgES256 = -7
+	COSEAlgEdDSA = -8
+	COSEAlgRS256 = -257
+)
+
+// COSE 公钥
+type COSEKey struct {
+	Algorithm int64
+	PublicKey crypto.PublicKey
+}
+
+// 解析 CBOR 编码的 COSE 公钥，支持 ES256（P-256）、EdDSA（Ed25519）、RS256
+func ParseCOSEKey(data []byte) (*COSEKey, error) {
+	value, rest, err := DecodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after cose key")
+	}
+	entries, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("cose key must be a map")
+	}
+
+	kty, _ := entries[int64(1)].(int64)
+	alg, _ := entries[int64(3)].(int64)
+	param := func(label int64) []byte {
+		b, _ := entries[label].([]byte)
+		return b
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+
+	var jwk JWK
+	switch {
+	case kty == 2 && alg == COSEAlgES256:
+		// EC2：-1 曲线（1 = P-256），-2 X，-3 Y
+		if crv, _ := entries[int64(-1)].(int64); crv != 1 {
+			return nil, fmt.Errorf("unsupported cose curve %d", crv)
+		}
+		x, y := param(-2), param(-3)
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid ec public key")
+		}
+		jwk = JWK{Kty: "EC", Crv: "P-256", X: encode(x), Y: encode(y)}
+	case kty == 1 && alg == COSEAlgEdDSA:
+		// OKP：-1 曲线（6 = Ed25519），-2 公钥
+		if crv, _ := entries[int64(-1)].(int64); crv != 6 {
+			return nil, fmt.Errorf("unsupported cose curve %d", crv)
+		}
+		jwk = JWK{Kty: "OKP", Crv: "Ed25519", X: encode(param(-2))}
+	case kty == 3 && alg == COSEAlgRS256:
+		// RSA：-1 n，-2 e
+		n, e := param(-1), param(-2)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid rsa public key")
+		}
+		jwk = JWK{Kty: "RSA", N: encode(n), E: encode(e)}
+	default:
+		return nil, fmt.Errorf("unsupported cose key type %d with algorithm %d", kty, alg)
+	}
+
+	publicKey, err := JWKToPublicKey(jwk)
+	if err != nil {
+		return nil, err
+	}
+	return &COSEKey{Algorithm: alg, PublicKey: publicKey}, nil
+}
+
+// 校验签名：ES256 为 ASN.1 DER 格式，RS256 为 PKCS#1 v1.5
+func (k *COSEKey) Verify(data, signature []byte) bool {
+	switch k.Algorithm {
+	case COSEAlgES256:
+		publicKey, ok := k.PublicKey.(*ecdsa.PublicKey)
+		digest := sha256.Sum256(data)
+		return ok && ecdsa.VerifyASN1(publicKey, digest[:], signature)
+	case COSEAlgEdDSA:
+		publicKey, ok := k.PublicKey.(ed25519.PublicKey)
+		return ok && ed25519.Verify(publicKey, data, signature)
+	case COSEAlgRS256:
+		publicKey, ok := k.PublicKey.(*rsa.PublicKey)
+		digest := sha256.Sum256(data)
+		return ok && rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
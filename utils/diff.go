@@ -0,0 +1,152 @@
+package utils
+
+import "strings"
+
+// 行级差异的操作类型
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// 一行差异：OldLine、NewLine 为从 1 开始的行号，0 表示该侧没有这一行
+type DiffLine struct {
+	Op      string `json:"op"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	Text    string `json:"text"`
+}
+
+// 差异步数（插入、删除的行数之和）上限：回溯需要保存每一步的搜索状态，内存与步数的平方成正比，
+// 超过上限时按整体替换返回（旧内容全部删除，新内容全部插入）
+const DiffMaxEdits = 1000
+
+// 按行比较两段文本（Myers 算法，得到最短编辑序列），删除行排在插入行之前
+func DiffLines(oldText, newText string) []DiffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	// 相同的首尾行不参与搜索，修改集中在局部时搜索范围很小
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, DiffLine{Op: DiffEqual, OldLine: i + 1, NewLine: i + 1, Text: a[i]})
+	}
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	middle, ok := diffMyers(middleA, middleB, DiffMaxEdits)
+	if !ok {
+		middle = diffReplace(middleA, middleB)
+	}
+	for _, line := range middle {
+		if line.OldLine > 0 {
+			line.OldLine += prefix
+		}
+		if line.NewLine > 0 {
+			line.NewLine += prefix
+		}
+		lines = append(lines, line)
+	}
+	for i := suffix; i > 0; i-- {
+		lines = append(lines, DiffLine{Op: DiffEqual, OldLine: len(a) - i + 1, NewLine: len(b) - i + 1, Text: a[len(a)-i]})
+	}
+	return lines
+}
+
+// Myers 算法，步数超过 maxEdits 时返回 false
+func diffMyers(a, b []string, maxEdits int) ([]DiffLine, bool) {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	// 前向搜索：记录每一步开始前对角线 -d-1..d+1 上到达的最远 x（回溯只会用到这些对角线）
+search:
+	for d := 0; d <= limit; d++ {
+		if d > maxEdits {
+			return nil, false
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // 下移：插入 b 的一行
+			} else {
+				x = v[offset+k-1] + 1 // 右移：删除 a 的一行
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// 回溯：从终点倒推每一步的编辑，结果为倒序
+	var reversed []DiffLine
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		base := d + 1 // 对角线 k 在快照中的下标为 base+k
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[base+k-1] < v[base+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[base+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffLine{Op: DiffEqual, OldLine: x, NewLine: y, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			reversed = append(reversed, DiffLine{Op: DiffInsert, NewLine: y, Text: b[y-1]})
+		} else {
+			reversed = append(reversed, DiffLine{Op: DiffDelete, OldLine: x, Text: a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+
+	lines := make([]DiffLine, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines, true
+}
+
+// 整体替换：删除全部旧行，再插入全部新行
+func diffReplace(a, b []string) []DiffLine {
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	for i, text := range a {
+		lines = append(lines, DiffLine{Op: DiffDelete, OldLine: i + 1, Text: text})
+	}
+	for i, text := range b {
+		lines = append(lines, DiffLine{Op: DiffInsert, NewLine: i + 1, Text: text})
+	}
+	return lines
+}
+
+// 按行切分，空文本没有行，忽略末尾换行
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+}
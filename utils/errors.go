@@ -11,6 +11,7 @@ type AppError struct {
 	Code    int
 	Message string
 	Err     error
+	Details interface{} // 返回给客户端的附加信息，放在响应的 error 字段中
 }
 
 func (e *AppError) Error() string {
@@ -27,9 +28,22 @@ func NewAppError(code int, message string) *AppError {
 	}
 }
 
+// 带附加信息的错误
+func NewAppErrorWithDetails(code int, message string, details interface{}) *AppError {
+	return &AppError{
+		Code:    code,
+		Message: message,
+		Details: details,
+	}
+}
+
 func HandleError(c *gin.Context, err error) {
 	var appErr *AppError
 	if errors.As(err, &appErr) {
+		if appErr.Details != nil {
+			ErrorWithDetails(c, appErr.Code, appErr.Message, appErr.Details)
+			return
+		}
 		Error(c, appErr.Code, appErr.Message)
 		return
 	}
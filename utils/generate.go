@@ -1,6 +1,10 @@
 package utils
 
 import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"math/rand"
 	"time"
@@ -37,3 +41,18 @@ func GencOrderNo() string {
 // 	// 3. 拼接规则：时间戳(13位) + 机器ID(2位) + 序列(3位) = 18位
 // 	return fmt.Sprintf("%d%02d%03d", now, g.machineID, g.sequence%1000)
 // }
+
+// 生成随机令牌（URL 安全的 base64 编码，n 为随机字节数）
+func GenerateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// 令牌摘要：数据库只保存摘要，泄露数据库也拿不到原始令牌
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
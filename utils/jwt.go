@@ -7,34 +7,109 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// 令牌签发、校验参数（来自配置 jwt.*）
+type JWTOptions struct {
+	Secret   []byte        // HS256 共享密钥
+	Keys     *KeySet       // RS256/EdDSA 密钥，非空时使用非对称签名，Secret 不再使用
+	Expire   time.Duration // 访问令牌有效期
+	Issuer   string        // iss，为空时不签发也不校验
+	Audience string        // aud，为空时不签发也不校验
+	Leeway   time.Duration // 校验 exp/nbf/iat 时允许的时钟偏差
+}
+
+// 令牌类型：访问令牌为空，其他类型的令牌不能用于访问接口
+const (
+	TokenTypeTwoFactorChallenge = "2fa_challenge"
+	TokenTypePersonalAccess     = "pat" // 个人访问令牌，不是 JWT，校验后转换为 Claims
+)
+
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	UserID       uint     `json:"user_id"`
+	Username     string   `json:"username"`
+	TokenVersion uint     `json:"ver"` // 用户令牌版本，"退出所有设备"后旧版本令牌全部失效
+	Role         string   `json:"role"`
+	Permissions  []string `json:"perms,omitempty"`
+	TokenType    string   `json:"typ,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"` // 个人访问令牌的授权范围
+	SessionID    uint     `json:"sid,omitempty"`    // 登录会话，会话撤销后令牌失效
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(secret []byte, userID uint, username string) (string, error) {
-	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+// 签发令牌：调用方填写业务字段，jti/iss/aud/exp/iat/nbf 由此处统一设置
+func GenerateToken(opts *JWTOptions, claims Claims) (string, error) {
+	return GenerateTokenWithExpire(opts, claims, opts.Expire)
+}
+
+// 签发指定有效期的令牌（如两步验证挑战令牌）
+func GenerateTokenWithExpire(opts *JWTOptions, claims Claims, expire time.Duration) (string, error) {
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		Issuer:    opts.Issuer,
+		ExpiresAt: jwt.NewNumericDate(now.Add(expire)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+	if opts.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{opts.Audience}
+	}
+
+	if opts.Keys == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(opts.Secret)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
+	key := opts.Keys.Current()
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(key.Private)
 }
 
-func ParseToken(tokenString string, secret []byte) (*Claims, error) {
+func ParseToken(tokenString string, opts *JWTOptions) (*Claims, error) {
+	algorithms := []string{AlgHS256}
+	if opts.Keys != nil {
+		algorithms = opts.Keys.Algorithms()
+	}
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(opts.Audience))
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if opts.Keys == nil {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			return opts.Secret, nil
+		}
+
+		// 按 kid 选择验签公钥
+		kid, _ := token.Header["kid"].(string)
+		key := opts.Keys.Lookup(kid)
+		if key == nil {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Algorithm {
 			return nil, errors.New("unexpected signing method")
 		}
-		return secret, nil
-	})
+		return key.Public, nil
+	}, parserOptions...)
 
 	if err != nil {
 		return nil, err
@@ -46,4 +121,3 @@ func ParseToken(tokenString string, secret []byte) (*Claims, error) {
 
 	return nil, errors.New("invalid token")
 }
-
@@ -0,0 +1,309 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 查询语言：
+//
+//	query  = or
+//	or     = and { "OR" and }
+//	and    = unary { unary }              // 空格分隔表示同时满足
+//	unary  = "-" unary | "(" or ")" | term // - 表示取反
+//	term   = [ field ":" ] value           // 没有 field 时为关键词
+//	value  = word | "\"" 带空格的短语 "\""
+//
+// 例如：title:gin author:admin comments:>=3 created:2026-01..2026-02 -tag:draft (tag:go OR tag:web)。
+// 这里只负责解析为语法树，字段的含义和取值由调用方解释
+
+const (
+	QueryMaxLength = 200 // 查询语句长度上限（字符）
+	QueryMaxTerms  = 20  // 条件个数上限
+	QueryMaxDepth  = 5   // 括号嵌套层数上限
+)
+
+// 语法树节点
+type QueryNode interface {
+	// 节点在查询语句中的位置（从 0 开始的字符偏移）
+	Pos() int
+}
+
+// 同时满足全部子条件
+type QueryAnd struct {
+	Nodes    []QueryNode
+	Position int
+}
+
+// 满足任意一个子条件
+type QueryOr struct {
+	Nodes    []QueryNode
+	Position int
+}
+
+// 不满足子条件
+type QueryNot struct {
+	Node     QueryNode
+	Position int
+}
+
+// 条件：Field 为空表示关键词。Field 已转为小写
+type QueryTerm struct {
+	Field         string
+	Value         string
+	Quoted        bool
+	Position      int // 条件的位置
+	ValuePosition int // 取值的位置
+}
+
+func (n *QueryAnd) Pos() int  { return n.Position }
+func (n *QueryOr) Pos() int   { return n.Position }
+func (n *QueryNot) Pos() int  { return n.Position }
+func (n *QueryTerm) Pos() int { return n.Position }
+
+// 查询语句错误：Position 为出错处的字符偏移（从 0 开始），Near 为出错处的原文
+type QueryError struct {
+	Position int    `json:"position"`
+	Near     string `json:"near"`
+	Message  string `json:"message"`
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d near %q", e.Message, e.Position, e.Near)
+}
+
+type queryTokenKind int
+
+const (
+	queryTokenEOF queryTokenKind = iota
+	queryTokenTerm
+	queryTokenOr
+	queryTokenNot
+	queryTokenLParen
+	queryTokenRParen
+)
+
+type queryToken struct {
+	kind     queryTokenKind
+	text     string // 原文
+	position int
+	term     *QueryTerm
+}
+
+// 解析查询语句
+func ParseQuery(input string) (QueryNode, error) {
+	runes := []rune(input)
+	if len(runes) > QueryMaxLength {
+		return nil, &QueryError{Position: QueryMaxLength, Near: string(runes[QueryMaxLength:min(len(runes), QueryMaxLength+10)]),
+			Message: fmt.Sprintf("Query longer than %d characters", QueryMaxLength)}
+	}
+	tokens, err := lexQuery(runes)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	node, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if token := p.peek(); token.kind != queryTokenEOF {
+		return nil, token.error("Unexpected " + token.text)
+	}
+	return node, nil
+}
+
+// 词法分析
+func lexQuery(runes []rune) ([]queryToken, error) {
+	var tokens []queryToken
+	terms := 0
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: queryTokenLParen, text: "(", position: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: queryTokenRParen, text: ")", position: i})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			// 只有紧跟条件的 - 表示取反，单词中间的 - 是普通字符
+			tokens = append(tokens, queryToken{kind: queryTokenNot, text: "-", position: i})
+			i++
+		default:
+			start := i
+			term, next, err := lexQueryTerm(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			i = next
+			text := string(runes[start:next])
+			if text == "OR" {
+				tokens = append(tokens, queryToken{kind: queryTokenOr, text: text, position: start})
+				continue
+			}
+			if terms++; terms > QueryMaxTerms {
+				return nil, &QueryError{Position: start, Near: text, Message: fmt.Sprintf("Query has more than %d terms", QueryMaxTerms)}
+			}
+			tokens = append(tokens, queryToken{kind: queryTokenTerm, text: text, position: start, term: term})
+		}
+	}
+	return append(tokens, queryToken{kind: queryTokenEOF, text: "end of query", position: len(runes)}), nil
+}
+
+// 读取一个条件：field:value、field:"value"、value 或 "value"
+func lexQueryTerm(runes []rune, start int) (*QueryTerm, int, error) {
+	term := &QueryTerm{Position: start, ValuePosition: start}
+	i := start
+	// 字段名只能由字母和下划线组成，否则整个单词作为关键词
+	for i < len(runes) && (unicode.IsLetter(runes[i]) || runes[i] == '_') && runes[i] < unicode.MaxASCII {
+		i++
+	}
+	if i > start && i < len(runes) && runes[i] == ':' {
+		term.Field = strings.ToLower(string(runes[start:i]))
+		i++
+		term.ValuePosition = i
+	} else {
+		i = start
+	}
+
+	if i < len(runes) && runes[i] == '"' {
+		value, next, err := lexQueryQuoted(runes, i)
+		if err != nil {
+			return nil, 0, err
+		}
+		term.Value, term.Quoted = value, true
+		return term, next, nil
+	}
+	valueStart := i
+	for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+		i++
+	}
+	term.Value = string(runes[valueStart:i])
+	if term.Value == "" {
+		return nil, 0, &QueryError{Position: valueStart, Near: string(runes[start:i]), Message: "Missing value"}
+	}
+	return term, i, nil
+}
+
+// 读取双引号内的短语，\" 表示双引号，\\ 表示反斜杠
+func lexQueryQuoted(runes []rune, start int) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+				i++
+			}
+			b.WriteRune(runes[i])
+		case '"':
+			if b.Len() == 0 {
+				return "", 0, &QueryError{Position: start, Near: `""`, Message: "Empty phrase"}
+			}
+			return b.String(), i + 1, nil
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+	return "", 0, &QueryError{Position: start, Near: string(runes[start:min(len(runes), start+10)]), Message: "Unterminated quote"}
+}
+
+func (t queryToken) error(message string) *QueryError {
+	return &QueryError{Position: t.position, Near: t.text, Message: message}
+}
+
+// 递归下降语法分析
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	token := p.tokens[p.pos]
+	if token.kind != queryTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *queryParser) parseOr(depth int) (QueryNode, error) {
+	first, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []QueryNode{first}
+	for p.peek().kind == queryTokenOr {
+		p.next()
+		node, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return first, nil
+	}
+	return &QueryOr{Nodes: nodes, Position: first.Pos()}, nil
+}
+
+func (p *queryParser) parseAnd(depth int) (QueryNode, error) {
+	var nodes []QueryNode
+	for {
+		switch p.peek().kind {
+		case queryTokenEOF, queryTokenRParen, queryTokenOr:
+			if len(nodes) == 0 {
+				token := p.peek()
+				return nil, token.error("Expected search term before " + token.text)
+			}
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return &QueryAnd{Nodes: nodes, Position: nodes[0].Pos()}, nil
+		}
+		node, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+func (p *queryParser) parseUnary(depth int) (QueryNode, error) {
+	token := p.next()
+	switch token.kind {
+	case queryTokenNot:
+		switch p.peek().kind {
+		case queryTokenTerm, queryTokenLParen:
+		default:
+			return nil, p.peek().error("Expected search term after -")
+		}
+		node, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryNot{Node: node, Position: token.position}, nil
+	case queryTokenLParen:
+		if depth >= QueryMaxDepth {
+			return nil, token.error(fmt.Sprintf("Parentheses nested deeper than %d levels", QueryMaxDepth))
+		}
+		node, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != queryTokenRParen {
+			return nil, token.error("Missing closing parenthesis")
+		}
+		p.next()
+		return node, nil
+	case queryTokenTerm:
+		return token.term, nil
+	}
+	return nil, token.error("Unexpected " + token.text)
+}
@@ -29,6 +29,14 @@ func Error(c *gin.Context, code int, message string) {
 	})
 }
 
+func ErrorWithDetails(c *gin.Context, code int, message string, details interface{}) {
+	c.JSON(code, Response{
+		Code:    code,
+		Message: message,
+		Error:   details,
+	})
+}
+
 func ValidationError(c *gin.Context, errors map[string]string) {
 	c.JSON(http.StatusUnprocessableEntity, Response{
 		Code:    422,
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 文件存储：name 为以 / 分隔的相对路径，如 avatars/1-xxxx-128.png
+type Storage interface {
+	Put(name string, data []byte) error
+	Delete(name string) error
+	URL(name string) string
+}
+
+// 本地目录存储：文件通过 BaseURL（静态文件路由）对外访问
+type LocalStorage struct {
+	Dir     string
+	BaseURL string
+}
+
+func (s *LocalStorage) Put(name string, data []byte) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// 先写临时文件再改名，读取方不会看到写了一半的文件
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *LocalStorage) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(name string) string {
+	return strings.TrimSuffix(s.BaseURL, "/") + "/" + name
+}
+
+// 文件路径，不允许跳出存储目录
+func (s *LocalStorage) path(name string) (string, error) {
+	if name == "" || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", errors.New("invalid file name: " + name)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(name)), nil
+}
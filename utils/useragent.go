@@ -0,0 +1,47 @@
+package utils
+
+import "strings"
+
+// 浏览器、系统识别规则：按顺序匹配，Edge、Opera 的 User-Agent 中同时包含 Chrome，需要排在前面
+var (
+	userAgentBrowsers = [][2]string{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	userAgentSystems = [][2]string{
+		{"Windows", "Windows"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// 由 User-Agent 生成简短的设备描述，如 "Chrome on Windows"，无法识别时返回 "Unknown device"
+func DescribeUserAgent(userAgent string) string {
+	browser := matchUserAgent(userAgent, userAgentBrowsers)
+	system := matchUserAgent(userAgent, userAgentSystems)
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return "Unknown device"
+}
+
+func matchUserAgent(userAgent string, rules [][2]string) string {
+	for _, rule := range rules {
+		if strings.Contains(userAgent, rule[0]) {
+			return rule[1]
+		}
+	}
+	return ""
+}